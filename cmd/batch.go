@@ -1,34 +1,45 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/rickhallett/beautifi/internal/budget"
+	"github.com/rickhallett/beautifi/internal/config"
+	"github.com/rickhallett/beautifi/internal/pool"
 	"github.com/spf13/cobra"
 )
 
-var (
-	batchParallel int
-)
+var batchResume bool
 
 var batchCmd = &cobra.Command{
 	Use:   "batch [projects...]",
 	Short: "Generate logos for multiple projects",
 	Long: `Run generate command for multiple projects in sequence or parallel.
 
-If no projects specified, processes all projects in config directory.`,
+If no projects specified, processes all projects in config directory.
+Images that already exist are skipped unless --force is given.
+
+--parallel bounds both the number of projects processed at once and the
+number of concurrent generations within each project.`,
 	RunE: runBatch,
 }
 
 func init() {
 	rootCmd.AddCommand(batchCmd)
 
-	batchCmd.Flags().IntVarP(&batchParallel, "parallel", "p", 1, "number of parallel generations")
+	addBudgetFlags(batchCmd)
+	addGenerationFlags(batchCmd)
 	batchCmd.Flags().IntVarP(&variants, "variants", "n", 1, "number of variants per combination")
+	batchCmd.Flags().BoolVar(&batchResume, "resume", true, "skip images that already exist with matching metadata")
+	batchCmd.Flags().BoolVar(&force, "force", false, "regenerate existing images (overrides --resume)")
 	batchCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be generated")
 	batchCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	addBackendFlags(batchCmd)
+	addRunFlags(batchCmd)
 }
 
 func runBatch(cmd *cobra.Command, args []string) error {
@@ -37,18 +48,14 @@ func runBatch(cmd *cobra.Command, args []string) error {
 	if len(args) > 0 {
 		projects = args
 	} else {
-		// Discover all projects in config dir
-		projectsDir := filepath.Join(cfgDir, "projects")
-		entries, err := os.ReadDir(projectsDir)
+		// Discover all projects in config dir, skipping shared base files
+		files, err := config.ProjectFiles(filepath.Join(cfgDir, "projects"))
 		if err != nil {
 			return fmt.Errorf("failed to read projects directory: %w", err)
 		}
 
-		for _, entry := range entries {
-			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".yaml") {
-				name := strings.TrimSuffix(entry.Name(), ".yaml")
-				projects = append(projects, name)
-			}
+		for _, f := range files {
+			projects = append(projects, strings.TrimSuffix(filepath.Base(f), ".yaml"))
 		}
 	}
 
@@ -58,17 +65,74 @@ func runBatch(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("Batch processing %d projects: %v\n\n", len(projects), projects)
 
-	for i, proj := range projects {
-		fmt.Printf("━━━ [%d/%d] %s ━━━\n", i+1, len(projects), proj)
+	// generate and batch share flag variables but differ in default
+	resume = batchResume
+	runBudget = &budget.Budget{MaxCost: maxCost, MaxImages: maxImages}
 
-		// Re-use generate command logic
-		err := runGenerate(cmd, []string{proj})
-		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+	start := time.Now()
+	summaries := make([]*projectSummary, len(projects))
+	errs := make([]error, len(projects))
+
+	ctx := cmd.Context()
+	pool.Run(len(projects), parallel, func(i int) {
+		if ctx.Err() != nil {
+			errs[i] = errInterrupted
+			return
+		}
+		fmt.Printf("━━━ [%d/%d] %s ━━━\n", i+1, len(projects), projects[i])
+
+		summaries[i], errs[i] = generateProject(cmd, projects[i])
+		if errs[i] != nil {
 			// Continue with other projects
+			fmt.Printf("Error (%s): %v\n", projects[i], errs[i])
 		}
-		fmt.Println()
-	}
+	})
+
+	printBatchSummary(projects, summaries, errs, time.Since(start))
 
+	if ctx.Err() != nil {
+		return interrupted(cmd)
+	}
 	return nil
 }
+
+func printBatchSummary(projects []string, summaries []*projectSummary, errs []error, elapsed time.Duration) {
+	fmt.Printf("\n━━━ Summary ━━━\n")
+
+	status := "Complete"
+	success, total := 0, 0
+	for i, name := range projects {
+		if errors.Is(errs[i], errInterrupted) || (summaries[i] != nil && summaries[i].Cancelled) {
+			status = "Interrupted"
+		}
+		switch {
+		case errs[i] != nil:
+			fmt.Printf("  %-24s error: %v\n", name, firstLine(errs[i].Error()))
+		case summaries[i] == nil:
+			fmt.Printf("  %-24s dry run\n", name)
+		default:
+			s := summaries[i]
+			note := ""
+			if s.Cancelled {
+				note = ", interrupted"
+			}
+			fmt.Printf("  %-24s %d/%d images, %d already present (%s%s)\n",
+				name, s.succeeded(), len(s.Results), s.skipped(), s.Elapsed.Round(time.Second), note)
+			success += s.succeeded()
+			total += len(s.Results)
+		}
+	}
+
+	fmt.Printf("\n%s: %d/%d images generated across %d projects in %s\n",
+		status, success, total, len(projects), elapsed.Round(time.Second))
+	if cost, _ := runBudget.Spent(); cost > 0 {
+		fmt.Printf("Estimated cost: $%.2f\n", cost)
+	}
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rickhallett/beautifi/internal/api"
+	"github.com/rickhallett/beautifi/internal/config"
+	"github.com/rickhallett/beautifi/internal/generator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	validateBackend string
+	showResolved    bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show settings, validate project configs and print the config schema",
+
+	// Settings problems are reported by config show and validate rather
+	// than stopping them
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		applySettings(cmd)
+		return nil
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the user config file, or the effective settings",
+	Long: `Print config.yaml from the config directory.
+
+With --resolved, print the value every setting takes and where it comes
+from: a flag, a BEAUTIFI_* environment variable, config.yaml or the
+built-in default. Defaults are those of generate; --output-dir and
+--config-dir given here are shown as flags.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigShow,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [project...]",
+	Short: "Check project configs (default: all projects)",
+	Long: `Check project configs for syntax errors, unknown or misspelled keys,
+wrong value types, missing required fields, unknown styles, invalid prompt
+templates and aspect ratios the backend does not support.
+
+config.yaml in the config directory is checked too. Problems are
+reported as file:line:column.`,
+	RunE: runConfigValidate,
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for project files",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		os.Stdout.Write(config.ProjectSchema)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd, configValidateCmd, configSchemaCmd)
+	configShowCmd.Flags().BoolVar(&showResolved, "resolved", false, "print effective settings and their sources")
+
+	configValidateCmd.Flags().StringVar(&validateBackend, "backend", api.DefaultBackend, "backend to check capabilities against ("+strings.Join(api.Backends(), ", ")+")")
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	s, problems, err := config.ParseSettings(cfgDir, api.Backends())
+	if err != nil {
+		return err
+	}
+	if errs := config.Errors(problems); len(errs) > 0 {
+		for _, p := range errs {
+			fmt.Fprintln(os.Stderr, p)
+		}
+		cmd.SilenceUsage = true
+		return fmt.Errorf("invalid %s", config.SettingsPath(cfgDir))
+	}
+
+	if !showResolved {
+		path := config.SettingsPath(cfgDir)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			fmt.Printf("No %s; using built-in defaults\n", path)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		fmt.Printf("# %s\n", path)
+		os.Stdout.Write(data)
+		return nil
+	}
+
+	cfgSource := "default"
+	if cmd.Flags().Changed("config-dir") {
+		cfgSource = "flag --config-dir"
+	} else if os.Getenv("BEAUTIFI_CONFIG_DIR") != "" {
+		cfgSource = "env BEAUTIFI_CONFIG_DIR"
+	}
+
+	// Persistent flags come from this command; the rest are resolved
+	// against generate's defaults
+	lookup := func(name string) *pflag.Flag {
+		if f := cmd.Flag(name); f != nil {
+			return f
+		}
+		return generateCmd.Flags().Lookup(name)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	fmt.Fprintf(w, "config_dir\t%s\t%s\n", cfgDir, cfgSource)
+	backend := api.DefaultBackend
+	for _, r := range resolveSettings(s, lookup) {
+		switch {
+		case r.Key == "backend":
+			backend = r.Value
+		case r.Key == "model" && r.Source == "default":
+			r.Value = api.BackendModel(backend)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.Key, r.Value, r.Source)
+	}
+	return w.Flush()
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	paths, err := projectPaths(args)
+	if err != nil {
+		return err
+	}
+
+	errCount, warnCount := 0, 0
+	report := func(problems []config.Problem) {
+		for _, p := range problems {
+			fmt.Println(p)
+			if p.Warning {
+				warnCount++
+			} else {
+				errCount++
+			}
+		}
+	}
+
+	_, problems, err := config.ParseSettings(cfgDir, api.Backends())
+	if err != nil {
+		return err
+	}
+	report(problems)
+
+	if len(paths) == 0 && errCount == 0 {
+		fmt.Printf("No projects in %s\n", filepath.Join(cfgDir, "projects"))
+		return nil
+	}
+
+	for _, path := range paths {
+		problems, err := validateProject(path, validateBackend)
+		if err != nil {
+			return err
+		}
+		report(problems)
+	}
+
+	if errCount == 0 {
+		fmt.Printf("%d projects OK", len(paths))
+		if warnCount > 0 {
+			fmt.Printf(" (%d warnings)", warnCount)
+		}
+		fmt.Println()
+		return nil
+	}
+	cmd.SilenceUsage = true
+	return fmt.Errorf("%d problems found", errCount)
+}
+
+// projectPaths returns config paths for the named projects, or all projects.
+// Base files are checked through the projects that extend them.
+func projectPaths(names []string) ([]string, error) {
+	dir := filepath.Join(cfgDir, "projects")
+	if len(names) == 0 {
+		return config.ProjectFiles(dir)
+	}
+
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = filepath.Join(dir, name+".yaml")
+	}
+	return paths, nil
+}
+
+// validateProject parses a project file and checks it against the style
+// presets, the prompt templates and the backend's capabilities
+func validateProject(path, backendName string) ([]config.Problem, error) {
+	doc, problems, err := config.ParseProject(path)
+	if err != nil {
+		return nil, err
+	}
+	proj := doc.Project
+	if proj == nil {
+		return problems, nil
+	}
+
+	caps := api.BackendCapabilities(backendName)
+	if !caps.SupportsAspectRatio(proj.AspectRatio) {
+		problems = append(problems, doc.Problem("aspect_ratio", "aspect ratio %q is not supported by %s (supported: %s)",
+			proj.AspectRatio, backendName, strings.Join(caps.AspectRatios, ", ")))
+	}
+	if n := proj.Generation.CandidateCount; n > 0 && caps.MaxImagesPerCall > 0 && n > caps.MaxImagesPerCall {
+		p := doc.Problem("generation.candidate_count", "candidate_count %d exceeds the %d images per call %s returns; requests will be split",
+			n, caps.MaxImagesPerCall, backendName)
+		p.Warning = true
+		problems = append(problems, p)
+	}
+	if _, err := generator.FormatMimeType(proj.OutputFormat); err != nil {
+		problems = append(problems, doc.Problem("output_format", "%v", err))
+	}
+
+	presets, err := config.LoadStyles(cfgDir, proj)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range unknownStyles(proj, presets) {
+		problems = append(problems, doc.Problem(p.key, "%s", p.msg))
+	}
+
+	// Rendering one variant of every prompt catches template syntax errors
+	// and references to missing extras
+	if _, err := generator.GeneratePrompts(proj, presets, proj.Styles, 1); err != nil {
+		problems = append(problems, doc.Problem("prompt_template", "%v", err))
+	}
+
+	config.SortProblems(problems)
+	return problems, nil
+}
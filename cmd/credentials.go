@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rickhallett/beautifi/internal/api"
+	"github.com/rickhallett/beautifi/internal/config"
+	"github.com/rickhallett/beautifi/internal/credentials"
+)
+
+var (
+	apiKeysMu sync.Mutex
+	apiKeys   = map[string]credentials.Key{} // by backend, so helpers run once
+)
+
+// apiKey resolves the API key for a backend: environment, .env in the
+// working or config directory, then the key file or api_key_command from
+// config.yaml. Backends that need no key get an empty key.
+func apiKey(ctx context.Context, backendName string) (credentials.Key, error) {
+	names := api.BackendCapabilities(backendName).APIKeys
+	if len(names) == 0 {
+		return credentials.Key{}, nil
+	}
+
+	apiKeysMu.Lock()
+	defer apiKeysMu.Unlock()
+	if key, ok := apiKeys[backendName]; ok {
+		return key, nil
+	}
+
+	s := userSettings
+	if s == nil {
+		s = &config.Settings{}
+	}
+	sources := credentials.Sources{
+		Names:   names,
+		EnvDirs: []string{".", cfgDir},
+		KeyDir:  filepath.Join(cfgDir, "keys"),
+		KeyFile: expandHome(s.APIKeyFile),
+		Command: s.APIKeyCommand,
+	}
+	if c, ok := s.Credentials[backendName]; ok {
+		sources.KeyFile, sources.Command = expandHome(c.APIKeyFile), c.APIKeyCommand
+	}
+
+	key, err := credentials.Resolve(ctx, sources)
+	if errors.Is(err, credentials.ErrNotFound) {
+		vars := make([]string, len(names))
+		for i, n := range names {
+			vars[i] = credentials.EnvVar(n)
+		}
+		return key, fmt.Errorf("no API key for %s: set %s, add it to a .env file, or set api_key_file or api_key_command in %s",
+			backendName, strings.Join(vars, " or "), config.SettingsPath(cfgDir))
+	} else if err != nil {
+		return key, fmt.Errorf("%s API key: %w", backendName, err)
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Using %s for %s\n", key, backendName)
+	}
+	apiKeys[backendName] = key
+	return key, nil
+}
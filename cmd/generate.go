@@ -1,13 +1,20 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/rickhallett/beautifi/internal/api"
+	"github.com/rickhallett/beautifi/internal/budget"
 	"github.com/rickhallett/beautifi/internal/config"
 	"github.com/rickhallett/beautifi/internal/generator"
+	"github.com/rickhallett/beautifi/internal/ledger"
+	"github.com/rickhallett/beautifi/internal/runs"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +24,27 @@ var (
 	dryRun     bool
 	verbose    bool
 	promptOnly bool
+
+	backendName string
+	modelName   string
+	parallel    int
+	retries     int
+	rpm         int
+	maxInFlight int
+	resume      bool
+	force       bool
+	changedOnly bool
+	maxCost     float64
+	maxImages   int
+
+	// runBudget caps spend across every project in this process
+	runBudget *budget.Budget
+
+	// Generation parameter overrides; applied only when given
+	aspectRatio string
+	imageSize   string
+	temperature float32
+	seed        int32
 )
 
 var generateCmd = &cobra.Command{
@@ -35,19 +63,162 @@ func init() {
 
 	generateCmd.Flags().IntVarP(&variants, "variants", "n", 1, "number of variants per combination")
 	generateCmd.Flags().StringSliceVarP(&styles, "styles", "s", []string{"all"}, "styles to generate (all, flat, gradient, etc.)")
+	// The styles setting is applied per project by styleFilter
+	generateCmd.Flags().SetAnnotation("styles", skipSettings, []string{"true"})
 	generateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be generated without calling API")
 	generateCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 	generateCmd.Flags().BoolVar(&promptOnly, "prompts-only", false, "only output prompts, no images")
+	generateCmd.Flags().BoolVar(&resume, "resume", false, "skip images that already exist with matching metadata")
+	generateCmd.Flags().BoolVar(&changedOnly, "changed-only", false, "only generate images that are missing or whose prompt, model or parameters changed")
+	generateCmd.Flags().BoolVar(&force, "force", false, "regenerate existing images (overrides --resume)")
+	addBudgetFlags(generateCmd)
+	addGenerationFlags(generateCmd)
+	addBackendFlags(generateCmd)
+	addRunFlags(generateCmd)
+}
+
+// addBudgetFlags registers the spend and image caps
+func addBudgetFlags(cmd *cobra.Command) {
+	cmd.Flags().Float64Var(&maxCost, "max-cost", 0, "refuse to start or stop once estimated spend would exceed this many USD (0 = no cap)")
+	cmd.Flags().IntVar(&maxImages, "max-images", 0, "refuse to start or stop once this many images would be requested (0 = no cap)")
+}
+
+// addBackendFlags registers the backend and model choice
+func addBackendFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&backendName, "backend", api.DefaultBackend, "image backend ("+strings.Join(api.Backends(), ", ")+")")
+	cmd.Flags().StringVar(&modelName, "model", "", "model to use instead of the backend's default")
+}
+
+// addRunFlags registers flags that control concurrency, rate limits and
+// retries of API requests
+func addRunFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVarP(&parallel, "parallel", "p", 1, "number of parallel generations")
+	cmd.Flags().IntVar(&retries, "retries", api.DefaultRetryPolicy().MaxAttempts-1, "retries for rate-limited or transient failures")
+	cmd.Flags().IntVar(&rpm, "rpm", 0, "max requests per minute per model (0 = model default)")
+	cmd.Flags().IntVar(&maxInFlight, "max-inflight", 0, "max concurrent requests per model (0 = model default)")
+}
+
+// addGenerationFlags registers flags that override project generation config
+func addGenerationFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&aspectRatio, "aspect-ratio", "", "override aspect ratio (e.g. 1:1, 16:9)")
+	cmd.Flags().StringVar(&imageSize, "image-size", "", "override image size (1K, 2K, 4K)")
+	cmd.Flags().Float32Var(&temperature, "temperature", 0, "override sampling temperature")
+	cmd.Flags().Int32Var(&seed, "seed", 0, "override seed (offset by variant number)")
+}
+
+// applyGenerationFlags overrides proj with generation flags set on cmd
+func applyGenerationFlags(cmd *cobra.Command, proj *config.Project) {
+	flags := cmd.Flags()
+	if flags.Changed("aspect-ratio") {
+		proj.AspectRatio = aspectRatio
+	}
+	if flags.Changed("image-size") {
+		proj.Generation.ImageSize = imageSize
+	}
+	if flags.Changed("temperature") {
+		t := temperature
+		proj.Generation.Temperature = &t
+	}
+	if flags.Changed("seed") {
+		s := seed
+		proj.Generation.Seed = &s
+	}
 }
 
 func runGenerate(cmd *cobra.Command, args []string) error {
-	projectName := args[0]
+	runBudget = &budget.Budget{MaxCost: maxCost, MaxImages: maxImages}
+
+	summary, err := generateProject(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	if summary == nil {
+		return nil
+	}
+
+	fmt.Printf("\n%s: %d/%d images generated", summary.status(), summary.succeeded(), len(summary.Results))
+	if n := summary.skipped(); n > 0 {
+		fmt.Printf(" (%d up to date)", n)
+	}
+	fmt.Println()
+	if cost, _ := runBudget.Spent(); cost > 0 {
+		fmt.Printf("Estimated cost: $%.2f\n", cost)
+	}
+	fmt.Printf("Output: %s\n", summary.OutDir)
+	fmt.Printf("Run: %s\n", summary.RunID)
+
+	if summary.Cancelled {
+		return interrupted(cmd)
+	}
+	return nil
+}
+
+// errInterrupted is returned by commands stopped with Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// interrupted reports a cancelled command without cobra's usage text;
+// Execute prints the error itself
+func interrupted(cmd *cobra.Command) error {
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return errInterrupted
+}
+
+// projectSummary is the outcome of generating one project
+type projectSummary struct {
+	Project   string
+	RunID     string
+	OutDir    string
+	Results   []generator.GenerationResult
+	Elapsed   time.Duration
+	Cancelled bool
+}
+
+func (s *projectSummary) status() string {
+	if s.Cancelled {
+		return "Interrupted"
+	}
+	return "Complete"
+}
+
+func (s *projectSummary) skipped() int {
+	n := 0
+	for _, r := range s.Results {
+		if r.Skipped {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *projectSummary) succeeded() int {
+	n := 0
+	for _, r := range s.Results {
+		if r.Success {
+			n++
+		}
+	}
+	return n
+}
+
+// generateProject runs generation for a single project. It returns a nil
+// summary when no images were requested (dry run or prompts only).
+func generateProject(cmd *cobra.Command, projectName string) (*projectSummary, error) {
+	ctx := cmd.Context()
 
 	// Load project config
 	cfgPath := filepath.Join(cfgDir, "projects", projectName+".yaml")
 	proj, err := config.LoadProject(cfgPath)
 	if err != nil {
-		return fmt.Errorf("failed to load project config: %w\n\nCreate config at: %s", err, cfgPath)
+		return nil, fmt.Errorf("failed to load project config: %w\n\nCreate config at: %s", err, cfgPath)
+	}
+	applyGenerationFlags(cmd, proj)
+	if _, err := generator.FormatMimeType(proj.OutputFormat); err != nil {
+		return nil, fmt.Errorf("%s: %w", cfgPath, err)
+	}
+	if caps := api.BackendCapabilities(backendName); !caps.SupportsAspectRatio(proj.AspectRatio) {
+		return nil, fmt.Errorf("aspect ratio %q is not supported by %s (supported: %s)",
+			proj.AspectRatio, backendName, strings.Join(caps.AspectRatios, ", "))
 	}
 
 	if verbose {
@@ -58,14 +229,23 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
-	// Filter styles if specified
-	activeStyles := proj.Styles
-	if len(styles) > 0 && styles[0] != "all" {
-		activeStyles = filterStyles(proj.Styles, styles)
+	requested, source := styleFilter(cmd)
+	activeStyles, err := activeStylesFor(proj, requested, source)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return nil, err
 	}
 
 	// Generate prompts
-	prompts := generator.GeneratePrompts(proj, activeStyles, variants)
+	presets, err := config.LoadStyles(cfgDir, proj)
+	if err != nil {
+		return nil, err
+	}
+	warnStyles(proj, presets, requested, source)
+	prompts, err := generator.GeneratePrompts(proj, presets, activeStyles, variants)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cfgPath, err)
+	}
 
 	if verbose || dryRun || promptOnly {
 		fmt.Printf("Generated %d prompts:\n\n", len(prompts))
@@ -76,46 +256,233 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	projectOutDir := filepath.Join(outDir, proj.Project)
+	skipExisting := resume && !force
+	skipCurrent := changedOnly && !force
+
+	todo := len(prompts)
+	switch {
+	case skipCurrent:
+		todo -= generator.CountCurrent(projectOutDir, prompts, modelFor(backendName))
+	case skipExisting:
+		todo -= generator.CountGenerated(projectOutDir, prompts)
+	}
+	estimate := estimateRun(projectOutDir, backendName, todo)
+
 	if dryRun || promptOnly {
-		fmt.Printf("Dry run complete. Would generate %d images.\n", len(prompts))
-		return nil
+		fmt.Printf("Dry run complete. Would generate %d images.\n", todo)
+		fmt.Printf("Estimate: %s\n", estimate)
+		if err := runBudget.Check(estimate); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		return nil, nil
+	}
+
+	if err := runBudget.Check(estimate); err != nil {
+		return nil, fmt.Errorf("refusing to start %s: %w", proj.Project, err)
+	}
+	if verbose {
+		fmt.Printf("Estimate: %s\n\n", estimate)
+	}
+
+	return executeRun(ctx, proj, prompts, backendName, generator.Options{
+		Parallel:    parallel,
+		Verbose:     verbose,
+		Resume:      skipExisting,
+		ChangedOnly: skipCurrent,
+		Budget:      runBudget,
+		Price:       estimate.PerImage,
+	}, "")
+}
+
+// rateLimit returns the effective rate limit for a model: the model
+// default overridden by --rpm and --max-inflight
+func rateLimit(model string) api.RateLimit {
+	limit := api.DefaultRateLimit(model)
+	if rpm > 0 {
+		limit.RequestsPerMinute = rpm
+	}
+	if maxInFlight > 0 {
+		limit.MaxInFlight = maxInFlight
+	}
+	return limit
+}
+
+// estimateRun estimates generating n images for a project, using the
+// durations of past runs with the same backend and model when available
+func estimateRun(projectOutDir, backendName string, n int) budget.Estimate {
+	model := modelFor(backendName)
+	price, priced := api.Pricing(backendName, model)
+
+	latency, samples := runs.AverageDuration(projectOutDir, backendName, model)
+	if samples == 0 {
+		latency = price.TypicalLatency
+	}
+
+	return budget.NewEstimate(n, price, priced, latency, samples, parallel, rateLimit(model))
+}
+
+// modelFor returns the model used with a backend: --model if given,
+// otherwise the backend's default
+func modelFor(backendName string) string {
+	if modelName != "" {
+		return modelName
 	}
+	return api.BackendModel(backendName)
+}
 
-	// Check API key
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return fmt.Errorf("GEMINI_API_KEY environment variable not set")
+// newBackend creates the named backend wrapped in the usage ledger, the
+// shared rate limiter and the retry policy configured by flags
+func newBackend(ctx context.Context, name, project string) (api.Backend, error) {
+	key, err := apiKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := api.NewBackendModel(name, key.Value(), modelName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s backend: %w", name, err)
 	}
 
+	// Record every individual attempt, so this sits innermost
+	backend = api.WithObserver(backend, recordUsage(project))
+
+	// Throttle inside the retry layer so every attempt is counted; the
+	// limiter is shared by all workers and projects in this process.
+	limit := rateLimit(backend.Model())
+	backend = api.WithRateLimit(backend, api.SharedLimiter(backend.Name(), backend.Model(), limit))
+
+	policy := api.DefaultRetryPolicy()
+	policy.MaxAttempts = retries + 1
+	return api.WithRetry(backend, policy), nil
+}
+
+// recordUsage returns an observer that appends each call to the usage ledger
+func recordUsage(project string) func(api.Call) {
+	usage := ledger.Open(cfgDir)
+	return func(c api.Call) {
+		entry := ledger.Entry{
+			Time:      c.Started,
+			Project:   project,
+			Backend:   c.Backend,
+			Model:     c.Model,
+			Style:     c.Labels["style"],
+			Success:   c.Err == nil,
+			Images:    c.Images,
+			LatencyMS: c.Latency.Milliseconds(),
+		}
+		switch {
+		case c.Err == nil:
+			price, _ := api.Pricing(c.Backend, c.Model)
+			entry.CostUSD = price.PerImage * float64(c.Images)
+		case errors.Is(c.Err, context.Canceled):
+			entry.ErrorKind = "cancelled"
+		default:
+			entry.ErrorKind = api.KindOf(c.Err).String()
+		}
+		if err := usage.Append(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record usage: %v\n", err)
+		}
+	}
+}
+
+// executeRun generates prompts for proj with the named backend and records
+// the run manifest. retryOf names the run being retried, if any. When ctx
+// is cancelled the manifest is still written and the summary is marked
+// cancelled.
+func executeRun(ctx context.Context, proj *config.Project, prompts []generator.PromptSpec, backendName string, opts generator.Options, retryOf string) (*projectSummary, error) {
+	start := time.Now()
+
+	backend, err := newBackend(ctx, backendName, proj.Project)
+	if err != nil {
+		return nil, err
+	}
+	defer backend.Close()
+
 	// Create output directory
 	projectOutDir := filepath.Join(outDir, proj.Project)
 	if err := os.MkdirAll(projectOutDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
 	// Generate images
-	client, err := api.NewGeminiClient(apiKey)
-	if err != nil {
-		return fmt.Errorf("failed to create Gemini client: %w", err)
+	results, err := generator.GenerateImages(ctx, backend, prompts, projectOutDir, opts)
+	cancelled := ctx.Err() != nil
+	if err != nil && !cancelled {
+		return nil, fmt.Errorf("generation failed: %w", err)
 	}
-	defer client.Close()
-	
-	results, err := generator.GenerateImages(client, prompts, projectOutDir, verbose)
-	if err != nil {
-		return fmt.Errorf("generation failed: %w", err)
+
+	// Record the run
+	manifest := &runs.Manifest{
+		ID:        runs.NewID(start),
+		Project:   proj.Project,
+		StartedAt: start,
+		EndedAt:   time.Now(),
+		Args:      os.Args[1:],
+		Backend:   backend.Name(),
+		Model:     backend.Model(),
+		RetryOf:   retryOf,
+		Cancelled: cancelled,
+		Config:    proj,
+		Results:   results,
+	}
+	if err := runs.Save(projectOutDir, manifest); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save run manifest: %v\n", err)
 	}
 
-	// Summary
-	success := 0
-	for _, r := range results {
-		if r.Success {
-			success++
+	return &projectSummary{
+		Project:   proj.Project,
+		RunID:     manifest.ID,
+		OutDir:    projectOutDir,
+		Results:   results,
+		Elapsed:   manifest.Duration(),
+		Cancelled: cancelled,
+	}, nil
+}
+
+// styleFilter returns the requested styles and where they came from:
+// "--styles", the styles setting's source, or "" when every style is wanted
+func styleFilter(cmd *cobra.Command) ([]string, string) {
+	if f := cmd.Flag("styles"); f != nil && f.Changed {
+		return styles, "--styles"
+	}
+	s := userSettings
+	if s == nil {
+		s = &config.Settings{}
+	}
+	for _, r := range resolveSettings(s, cmd.Flag) {
+		if r.Key != "styles" || r.Source == "default" || r.Value == "" {
+			continue
 		}
+		var requested []string
+		for _, name := range strings.Split(r.Value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				requested = append(requested, name)
+			}
+		}
+		return requested, r.Source
 	}
-	fmt.Printf("\nComplete: %d/%d images generated\n", success, len(results))
-	fmt.Printf("Output: %s\n", projectOutDir)
+	return nil, ""
+}
 
-	return nil
+// activeStylesFor returns the project styles selected by a styleFilter.
+// --styles matching none of them is an error rather than an empty run; the
+// styles setting is a default for every project, so a project that uses
+// none of its styles generates all of its own instead.
+func activeStylesFor(proj *config.Project, requested []string, source string) ([]string, error) {
+	if len(requested) == 0 || requested[0] == "all" {
+		return proj.Styles, nil
+	}
+	active := filterStyles(proj.Styles, requested)
+	if len(active) > 0 {
+		return active, nil
+	}
+	if source != "--styles" {
+		fmt.Fprintf(os.Stderr, "Warning: styles %s from %s match none of %s's styles %v; using all of them\n",
+			strings.Join(requested, ","), source, proj.Project, proj.Styles)
+		return proj.Styles, nil
+	}
+	return nil, fmt.Errorf("--styles %s matches none of the project's styles %v%s",
+		strings.Join(requested, ","), proj.Styles, didYouMean(requested[0], proj.Styles))
 }
 
 func filterStyles(available, requested []string) []string {
@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rickhallett/beautifi/internal/api"
+	"github.com/rickhallett/beautifi/internal/config"
+	"github.com/rickhallett/beautifi/internal/repoinfo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	initTagline     string
+	initThemes      []string
+	initStyles      []string
+	initAspectRatio string
+	initForce       bool
+	initNoInput     bool
+	initFromRepo    string
+	initSuggest     string
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init [project]",
+	Short: "Create a project config",
+	Long: `Create ~/.config/beautifi/projects/<project>.yaml.
+
+Asks for the tagline, themes, styles and aspect ratio unless they are given
+as flags. With --no-input, or when stdin is not a terminal, only flags are
+used and --themes is required.
+
+With --from-repo, the name, tagline and themes are proposed from the
+repository's README, go.mod, package.json or Cargo.toml (description and
+keywords). --suggest asks a text model instead; the stub backend answers
+offline.
+
+Examples:
+  beautifi init bosun
+  beautifi init bosun --tagline "Fleet manager" --themes ocean,navigation --styles flat-minimal,geometric
+  beautifi init --from-repo ~/src/bosun
+  beautifi init --from-repo ~/src/bosun --suggest gemini`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runInit,
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().StringVar(&initTagline, "tagline", "", "short description of the project")
+	initCmd.Flags().StringSliceVar(&initThemes, "themes", nil, "themes to generate (comma separated)")
+	initCmd.Flags().StringSliceVar(&initStyles, "styles", nil, "style presets to use (default: flat-minimal, gradient-glass)")
+	initCmd.Flags().StringVar(&initAspectRatio, "aspect-ratio", "", "aspect ratio (e.g. 1:1, 16:9)")
+	initCmd.Flags().BoolVarP(&initForce, "force", "f", false, "overwrite an existing config")
+	initCmd.Flags().BoolVar(&initNoInput, "no-input", false, "never prompt; use flags only")
+	initCmd.Flags().StringVar(&initFromRepo, "from-repo", "", "propose name, tagline and themes from a source repository")
+	initCmd.Flags().StringVar(&initSuggest, "suggest", "", "text model backend for --from-repo suggestions (gemini, stub)")
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	var info *repoinfo.Info
+	if initFromRepo != "" {
+		var err error
+		if info, err = repoinfo.Read(initFromRepo); err != nil {
+			return fmt.Errorf("read repository: %w", err)
+		}
+	} else if initSuggest != "" {
+		return fmt.Errorf("--suggest needs --from-repo")
+	}
+
+	var name string
+	switch {
+	case len(args) > 0:
+		name = args[0]
+	case info != nil:
+		name = sanitizeProjectName(info.Name)
+	default:
+		return fmt.Errorf("a project name is required (or use --from-repo)")
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid project name %q", name)
+	}
+
+	cfgPath := filepath.Join(cfgDir, "projects", name+".yaml")
+	if _, err := os.Stat(cfgPath); err == nil && !initForce {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", cfgPath)
+	}
+
+	presets, err := config.LoadStyles(cfgDir, nil)
+	if err != nil {
+		return err
+	}
+
+	proj := &config.Project{
+		Project:     name,
+		Tagline:     initTagline,
+		Themes:      initThemes,
+		Styles:      initStyles,
+		AspectRatio: initAspectRatio,
+	}
+
+	if info != nil {
+		suggestion, err := suggestFromRepo(cmd, info)
+		if err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("tagline") {
+			proj.Tagline = suggestion.Tagline
+		}
+		if !cmd.Flags().Changed("themes") {
+			proj.Themes = suggestion.Themes
+		}
+	}
+
+	if !initNoInput && isTerminal(os.Stdin) {
+		// Input ending early (e.g. stdin is /dev/null) keeps what was given
+		err := promptProject(cmd, bufio.NewReader(os.Stdin), proj, presets)
+		if errors.Is(err, io.EOF) {
+			fmt.Println()
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if len(proj.Themes) == 0 {
+		return fmt.Errorf("at least one theme is required (--themes)")
+	}
+	if len(proj.Styles) == 0 {
+		proj.Styles = []string{"flat-minimal", "gradient-glass"}
+	}
+	if err := checkAspectRatio(cmd, proj.AspectRatio); err != nil {
+		return fmt.Errorf("--aspect-ratio: %w", err)
+	}
+	for _, s := range proj.Styles {
+		if _, ok := presets[s]; !ok {
+			return fmt.Errorf("unknown style %q%s", s, didYouMean(s, config.StyleNames(presets)))
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfgPath), 0755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	if err := config.SaveProject(cfgPath, proj); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nWrote %s\n", cfgPath)
+	fmt.Printf("Next: beautifi preview %s\n", name)
+	return nil
+}
+
+// promptProject asks for every field not already set by flags
+func promptProject(cmd *cobra.Command, in *bufio.Reader, proj *config.Project, presets map[string]config.StylePreset) error {
+	flags := cmd.Flags()
+
+	if !flags.Changed("tagline") {
+		answer, err := ask(in, "Tagline", proj.Tagline)
+		if err != nil {
+			return err
+		}
+		proj.Tagline = answer
+	}
+
+	if !flags.Changed("themes") {
+		current := strings.Join(proj.Themes, ", ")
+		proj.Themes = nil
+		for len(proj.Themes) == 0 {
+			answer, err := ask(in, "Themes (comma separated)", current)
+			if err != nil {
+				return err
+			}
+			proj.Themes = splitList(answer)
+		}
+	}
+
+	if !flags.Changed("styles") {
+		names := config.StyleNames(presets)
+		fmt.Println("\nStyles:")
+		for i, n := range names {
+			fmt.Printf("  %2d. %-18s %s\n", i+1, n, presets[n].Description)
+		}
+		for {
+			answer, err := ask(in, "Styles (numbers or names, comma separated)", "flat-minimal, gradient-glass")
+			if err != nil {
+				return err
+			}
+			selected, err := selectStyles(splitList(answer), names)
+			if err == nil {
+				proj.Styles = selected
+				break
+			}
+			fmt.Printf("  %v\n", err)
+		}
+	}
+
+	if !flags.Changed("aspect-ratio") {
+		for {
+			answer, err := ask(in, "Aspect ratio", "1:1")
+			if err != nil {
+				return err
+			}
+			if err := checkAspectRatio(cmd, answer); err != nil {
+				fmt.Printf("  %v\n", err)
+				continue
+			}
+			proj.AspectRatio = answer
+			break
+		}
+	}
+	return nil
+}
+
+// checkAspectRatio applies config validate's aspect ratio checks against
+// the configured backend, so init never writes a config generate rejects
+func checkAspectRatio(cmd *cobra.Command, ratio string) error {
+	if err := config.CheckAspectRatio(ratio); err != nil {
+		return err
+	}
+	backend := api.DefaultBackend
+	if userSettings != nil {
+		for _, r := range resolveSettings(userSettings, cmd.Flag) {
+			if r.Key == "backend" && r.Value != "" {
+				backend = r.Value
+			}
+		}
+	}
+	if caps := api.BackendCapabilities(backend); !caps.SupportsAspectRatio(ratio) {
+		return fmt.Errorf("aspect ratio %q is not supported by %s (supported: %s)",
+			ratio, backend, strings.Join(caps.AspectRatios, ", "))
+	}
+	return nil
+}
+
+// suggestFromRepo proposes a tagline and themes for a repository, asking
+// the --suggest model if set and falling back to the repository's own text
+func suggestFromRepo(cmd *cobra.Command, info *repoinfo.Info) (repoinfo.Suggestion, error) {
+	fmt.Printf("Read %s\n", strings.Join(info.Sources, ", "))
+	suggestion := repoinfo.Suggest(info)
+	if initSuggest == "" {
+		return suggestion, nil
+	}
+
+	key, err := apiKey(cmd.Context(), initSuggest)
+	if err != nil {
+		return suggestion, err
+	}
+	backend, err := api.NewBackend(initSuggest, key.Value())
+	if err != nil {
+		return suggestion, err
+	}
+	defer backend.Close()
+
+	gen, ok := backend.(api.TextGenerator)
+	if !ok {
+		return suggestion, fmt.Errorf("backend %q cannot suggest text", initSuggest)
+	}
+	fromModel, err := repoinfo.SuggestWithModel(cmd.Context(), gen, info)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s suggestion failed, using the repository text: %v\n", initSuggest, err)
+		return suggestion, nil
+	}
+	return fromModel, nil
+}
+
+// sanitizeProjectName turns a repository name into a project name
+func sanitizeProjectName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.NewReplacer(" ", "-", "_", "-", "/", "-", "\\", "-").Replace(name)
+	return strings.TrimLeft(name, ".")
+}
+
+// ask prints a prompt and reads one line, returning def for an empty answer
+func ask(in *bufio.Reader, prompt, def string) (string, error) {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", prompt, def)
+	} else {
+		fmt.Printf("%s: ", prompt)
+	}
+	line, err := in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	if line = strings.TrimSpace(line); line == "" {
+		return def, nil
+	}
+	return line, nil
+}
+
+// selectStyles resolves a multi-select answer of list numbers or names
+func selectStyles(answers, names []string) ([]string, error) {
+	var selected []string
+	for _, a := range answers {
+		if n, err := strconv.Atoi(a); err == nil {
+			if n < 1 || n > len(names) {
+				return nil, fmt.Errorf("no style numbered %d", n)
+			}
+			a = names[n-1]
+		} else if !slices.Contains(names, a) {
+			return nil, fmt.Errorf("unknown style %q%s", a, didYouMean(a, names))
+		}
+		if !slices.Contains(selected, a) {
+			selected = append(selected, a)
+		}
+	}
+	return selected, nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
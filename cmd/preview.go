@@ -2,10 +2,12 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/rickhallett/beautifi/internal/api"
+	"github.com/rickhallett/beautifi/internal/budget"
 	"github.com/rickhallett/beautifi/internal/config"
 	"github.com/rickhallett/beautifi/internal/generator"
 	"github.com/spf13/cobra"
@@ -33,6 +35,11 @@ func init() {
 	previewCmd.Flags().IntVarP(&previewLimit, "limit", "l", 0, "limit number of prompts shown (0 = all)")
 	previewCmd.Flags().IntVarP(&variants, "variants", "n", 1, "number of variants per combination")
 	previewCmd.Flags().StringSliceVarP(&styles, "styles", "s", []string{"all"}, "styles to preview")
+	// The styles setting is applied per project by styleFilter
+	previewCmd.Flags().SetAnnotation("styles", skipSettings, []string{"true"})
+	previewCmd.Flags().StringVar(&backendName, "backend", api.DefaultBackend, "image backend to estimate cost for ("+strings.Join(api.Backends(), ", ")+")")
+	previewCmd.Flags().StringVar(&modelName, "model", "", "model to estimate cost for instead of the backend's default")
+	previewCmd.Flags().IntVarP(&parallel, "parallel", "p", 1, "parallel generations to estimate wall time for")
 }
 
 func runPreview(cmd *cobra.Command, args []string) error {
@@ -44,17 +51,27 @@ func runPreview(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load project config: %w", err)
 	}
 
-	// Check for existing outputs
-	projectOutDir := filepath.Join(outDir, proj.Project)
-	existingCount := countExistingImages(projectOutDir)
+	requested, source := styleFilter(cmd)
+	activeStyles, err := activeStylesFor(proj, requested, source)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
 
-	// Filter styles
-	activeStyles := proj.Styles
-	if len(styles) > 0 && styles[0] != "all" {
-		activeStyles = filterStyles(proj.Styles, styles)
+	presets, err := config.LoadStyles(cfgDir, proj)
+	if err != nil {
+		return err
+	}
+	warnStyles(proj, presets, requested, source)
+	prompts, err := generator.GeneratePrompts(proj, presets, activeStyles, variants)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cfgPath, err)
 	}
 
-	prompts := generator.GeneratePrompts(proj, activeStyles, variants)
+	// Check for existing outputs
+	projectOutDir := filepath.Join(outDir, proj.Project)
+	existingCount := generator.CountGenerated(projectOutDir, prompts)
+	estimate := estimateRun(projectOutDir, backendName, len(prompts))
 
 	if previewLimit > 0 && previewLimit < len(prompts) {
 		prompts = prompts[:previewLimit]
@@ -62,35 +79,25 @@ func runPreview(cmd *cobra.Command, args []string) error {
 
 	switch previewFormat {
 	case "json":
-		printPromptsJSON(proj, prompts)
+		printPromptsJSON(proj, prompts, estimate)
 	case "markdown":
-		printPromptsMarkdown(proj, prompts, existingCount)
+		printPromptsMarkdown(proj, prompts, existingCount, estimate)
 	default:
-		printPromptsText(proj, prompts, existingCount)
+		printPromptsText(proj, prompts, existingCount, estimate)
 	}
 
 	return nil
 }
 
-func countExistingImages(dir string) int {
-	count := 0
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err == nil && !info.IsDir() && strings.HasSuffix(path, ".png") {
-			count++
-		}
-		return nil
-	})
-	return count
-}
-
-func printPromptsText(proj *config.Project, prompts []generator.PromptSpec, existing int) {
+func printPromptsText(proj *config.Project, prompts []generator.PromptSpec, existing int, estimate budget.Estimate) {
 	fmt.Printf("Project: %s\n", proj.Project)
 	fmt.Printf("Tagline: %s\n", proj.Tagline)
 	fmt.Printf("Themes:  %v\n", proj.Themes)
 	fmt.Printf("Styles:  %v\n", proj.Styles)
 	if existing > 0 {
-		fmt.Printf("Existing: %d images\n", existing)
+		fmt.Printf("Existing: %d images (skipped with --resume)\n", existing)
 	}
+	fmt.Printf("Estimate: %s\n", estimate)
 	fmt.Printf("\n%d prompts to generate:\n", len(prompts))
 	fmt.Println(strings.Repeat("─", 60))
 
@@ -100,10 +107,12 @@ func printPromptsText(proj *config.Project, prompts []generator.PromptSpec, exis
 	}
 }
 
-func printPromptsJSON(proj *config.Project, prompts []generator.PromptSpec) {
+func printPromptsJSON(proj *config.Project, prompts []generator.PromptSpec, estimate budget.Estimate) {
 	fmt.Println("{")
 	fmt.Printf("  \"project\": \"%s\",\n", proj.Project)
 	fmt.Printf("  \"count\": %d,\n", len(prompts))
+	fmt.Printf("  \"estimated_cost_usd\": %.4f,\n", estimate.Cost)
+	fmt.Printf("  \"estimated_seconds\": %.0f,\n", estimate.WallTime.Seconds())
 	fmt.Println("  \"prompts\": [")
 	for i, p := range prompts {
 		comma := ","
@@ -117,7 +126,7 @@ func printPromptsJSON(proj *config.Project, prompts []generator.PromptSpec) {
 	fmt.Println("}")
 }
 
-func printPromptsMarkdown(proj *config.Project, prompts []generator.PromptSpec, existing int) {
+func printPromptsMarkdown(proj *config.Project, prompts []generator.PromptSpec, existing int, estimate budget.Estimate) {
 	fmt.Printf("# %s Logo Generation\n\n", proj.Project)
 	fmt.Printf("**Tagline:** %s\n\n", proj.Tagline)
 	fmt.Printf("| Metric | Value |\n")
@@ -128,7 +137,11 @@ func printPromptsMarkdown(proj *config.Project, prompts []generator.PromptSpec,
 	if existing > 0 {
 		fmt.Printf("| Existing | %d |\n", existing)
 	}
-	fmt.Println("\n## Prompts\n")
+	if estimate.Priced {
+		fmt.Printf("| Est. cost | $%.2f |\n", estimate.Cost)
+	}
+	fmt.Printf("| Est. time | %s |\n", estimate.WallTime.Round(time.Second))
+	fmt.Print("\n## Prompts\n\n")
 
 	for i, p := range prompts {
 		fmt.Printf("### %d. %s\n\n", i+1, p.Filename)
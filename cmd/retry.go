@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rickhallett/beautifi/internal/api"
+	"github.com/rickhallett/beautifi/internal/budget"
+	"github.com/rickhallett/beautifi/internal/generator"
+	"github.com/spf13/cobra"
+)
+
+var (
+	retryRunID   string
+	retryBackend string
+)
+
+var retryCmd = &cobra.Command{
+	Use:   "retry <project>",
+	Short: "Re-run only the failed generations of a previous run",
+	Long: `Re-submit the prompts that failed in a previous run.
+
+Uses the prompts, config snapshot and backend recorded in the run manifest,
+so successes are not regenerated. Defaults to the latest run.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRetry,
+}
+
+func init() {
+	rootCmd.AddCommand(retryCmd)
+
+	retryCmd.Flags().StringVar(&retryRunID, "run", "", "run ID to retry (default: latest run)")
+	retryCmd.Flags().StringVar(&retryBackend, "backend", "", "image backend (default: the backend of the original run; "+strings.Join(api.Backends(), ", ")+")")
+	retryCmd.Flags().StringVar(&modelName, "model", "", "model to use (default: the model of the original run)")
+
+	// Default to the original run, not to user settings
+	for _, name := range []string{"backend", "model"} {
+		retryCmd.Flags().SetAnnotation(name, skipSettings, []string{"true"})
+	}
+	addRunFlags(retryCmd)
+	addBudgetFlags(retryCmd)
+	retryCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+}
+
+func runRetry(cmd *cobra.Command, args []string) error {
+	runBudget = &budget.Budget{MaxCost: maxCost, MaxImages: maxImages}
+
+	projectOutDir := projectOutputDir(args[0])
+	prev, err := loadRun(projectOutDir, retryRunID)
+	if err != nil {
+		return err
+	}
+	if prev.Config == nil {
+		return fmt.Errorf("run %s has no config snapshot", prev.ID)
+	}
+
+	failed := prev.FailedSpecs()
+	if len(failed) == 0 {
+		fmt.Printf("Run %s has no failures to retry\n", prev.ID)
+		return nil
+	}
+
+	name := retryBackend
+	if name == "" {
+		name = prev.Backend
+		if modelName == "" && prev.Model != api.BackendModel(name) {
+			modelName = prev.Model
+		}
+	}
+
+	// Resubmissions are billed like any run
+	estimate := estimateRun(projectOutDir, name, len(failed))
+	if err := runBudget.Check(estimate); err != nil {
+		return fmt.Errorf("refusing to retry %s: %w", prev.ID, err)
+	}
+
+	fmt.Printf("Retrying %d/%d failed images from run %s\n", len(failed), len(prev.Results), prev.ID)
+	if verbose {
+		fmt.Printf("Estimate: %s\n", estimate)
+		for _, spec := range failed {
+			fmt.Printf("  %s\n", spec.Filename)
+		}
+	}
+
+	summary, err := executeRun(cmd.Context(), prev.Config, failed, name, generator.Options{
+		Parallel: parallel,
+		Verbose:  verbose,
+		Budget:   runBudget,
+		Price:    estimate.PerImage,
+	}, prev.ID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%s: %d/%d images generated\n", summary.status(), summary.succeeded(), len(summary.Results))
+	if n := len(summary.Results) - summary.succeeded(); n > 0 {
+		fmt.Printf("Still failing: %d (run `beautifi retry %s` again)\n", n, args[0])
+	}
+	if cost, _ := runBudget.Spent(); cost > 0 {
+		fmt.Printf("Estimated cost: $%.2f\n", cost)
+	}
+	fmt.Printf("Output: %s\n", summary.OutDir)
+	fmt.Printf("Run: %s\n", summary.RunID)
+
+	if summary.Cancelled {
+		return interrupted(cmd)
+	}
+	return nil
+}
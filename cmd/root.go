@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 )
@@ -18,19 +21,58 @@ var rootCmd = &cobra.Command{
 	Short:   "Batch logo generation CLI",
 	Long:    `beautifi v` + version + ` — Generate logos and icons using AI image generation.`,
 	Version: version,
+
+	// Defaults from config.yaml and BEAUTIFI_* variables apply to every
+	// command, under any flags given explicitly
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applySettings(cmd); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		return nil
+	},
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handleSignals(cancel)
+
+	err := rootCmd.ExecuteContext(ctx)
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
+	}
+	if ctx.Err() != nil {
+		os.Exit(130)
+	}
+	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// handleSignals cancels the command context on the first interrupt so
+// in-flight work can stop cleanly and summaries are still written; a
+// second interrupt exits immediately.
+func handleSignals(cancel context.CancelFunc) {
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	<-sigs
+	fmt.Fprintln(os.Stderr, "\nInterrupted: cancelling in-flight requests and writing summary (Ctrl-C again to force quit)")
+	cancel()
+
+	<-sigs
+	fmt.Fprintln(os.Stderr, "Force quit")
+	os.Exit(130)
+}
+
 func init() {
 	home, _ := os.UserHomeDir()
 	defaultCfg := home + "/.config/beautifi"
 	defaultOut := home + "/output/beautifi"
+	if dir := os.Getenv("BEAUTIFI_CONFIG_DIR"); dir != "" {
+		defaultCfg = dir
+	}
 
 	rootCmd.PersistentFlags().StringVar(&cfgDir, "config-dir", defaultCfg, "config directory")
 	rootCmd.PersistentFlags().StringVar(&outDir, "output-dir", defaultOut, "output directory")
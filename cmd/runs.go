@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rickhallett/beautifi/internal/config"
+	"github.com/rickhallett/beautifi/internal/generator"
+	"github.com/rickhallett/beautifi/internal/runs"
+	"github.com/spf13/cobra"
+)
+
+var runsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "Inspect the history of generate runs",
+	Long: `Inspect run manifests recorded by generate and batch.
+
+Each run writes a manifest to ~/output/beautifi/<project>/.runs/<run-id>.json
+with its arguments, backend, model, config snapshot and per-image results.`,
+}
+
+var runsListCmd = &cobra.Command{
+	Use:   "list <project>",
+	Short: "List recorded runs",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRunsList,
+}
+
+var runsShowCmd = &cobra.Command{
+	Use:   "show <project> [run-id]",
+	Short: "Show a run (default: latest)",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE:  runRunsShow,
+}
+
+var runsDiffCmd = &cobra.Command{
+	Use:   "diff <project> [run-id] [run-id]",
+	Short: "Compare two runs (default: the latest two)",
+	Args:  cobra.RangeArgs(1, 3),
+	RunE:  runRunsDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(runsCmd)
+	runsCmd.AddCommand(runsListCmd, runsShowCmd, runsDiffCmd)
+}
+
+// projectOutputDir returns the output dir for a project name as given on
+// the command line, preferring the name inside its config when readable
+func projectOutputDir(projectName string) string {
+	cfgPath := filepath.Join(cfgDir, "projects", projectName+".yaml")
+	if proj, err := config.LoadProject(cfgPath); err == nil {
+		return filepath.Join(outDir, proj.Project)
+	}
+	return filepath.Join(outDir, projectName)
+}
+
+func runRunsList(cmd *cobra.Command, args []string) error {
+	manifests, err := runs.List(projectOutputDir(args[0]))
+	if err != nil {
+		return err
+	}
+	if len(manifests) == 0 {
+		fmt.Printf("No runs recorded for %s\n", args[0])
+		return nil
+	}
+
+	fmt.Printf("%-24s  %-19s  %8s  %-24s  %s\n", "RUN", "STARTED", "DURATION", "BACKEND/MODEL", "IMAGES")
+	for _, m := range manifests {
+		fmt.Printf("%-24s  %-19s  %8s  %-24s  %d/%d",
+			m.ID,
+			m.StartedAt.Local().Format("2006-01-02 15:04:05"),
+			m.Duration().Round(time.Second),
+			m.Backend+"/"+m.Model,
+			m.Succeeded(), len(m.Results))
+		if m.Cancelled {
+			fmt.Printf(" (interrupted)")
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// loadRun loads the given run ID, or the latest run if id is empty
+func loadRun(projectOutDir, id string) (*runs.Manifest, error) {
+	if id == "" {
+		return runs.Latest(projectOutDir)
+	}
+	return runs.Load(projectOutDir, id)
+}
+
+func runRunsShow(cmd *cobra.Command, args []string) error {
+	id := ""
+	if len(args) > 1 {
+		id = args[1]
+	}
+	m, err := loadRun(projectOutputDir(args[0]), id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Run:      %s\n", m.ID)
+	fmt.Printf("Project:  %s\n", m.Project)
+	fmt.Printf("Started:  %s\n", m.StartedAt.Local().Format(time.RFC3339))
+	fmt.Printf("Duration: %s\n", m.Duration().Round(time.Millisecond))
+	fmt.Printf("Command:  beautifi %s\n", strings.Join(m.Args, " "))
+	fmt.Printf("Backend:  %s (%s)\n", m.Backend, m.Model)
+	if m.RetryOf != "" {
+		fmt.Printf("Retry of: %s\n", m.RetryOf)
+	}
+	fmt.Printf("Images:   %d/%d succeeded\n", m.Succeeded(), len(m.Results))
+	if m.Cancelled {
+		fmt.Printf("Status:   interrupted\n")
+	}
+	fmt.Println(strings.Repeat("─", 60))
+
+	for _, r := range m.Results {
+		fmt.Printf("%-6s %-40s %s\n", resultStatus(r), r.Spec.Filename, resultDetail(r))
+	}
+	return nil
+}
+
+func resultStatus(r generator.GenerationResult) string {
+	switch {
+	case r.Skipped:
+		return "skip"
+	case r.Success:
+		return "ok"
+	default:
+		return "FAIL"
+	}
+}
+
+func resultDetail(r generator.GenerationResult) string {
+	if !r.Success {
+		return r.Error
+	}
+	if r.Duration > 0 {
+		return r.Duration.Round(time.Millisecond).String()
+	}
+	return ""
+}
+
+func runRunsDiff(cmd *cobra.Command, args []string) error {
+	projectOutDir := projectOutputDir(args[0])
+
+	var a, b *runs.Manifest
+	switch len(args) {
+	case 3:
+		var err error
+		if a, err = runs.Load(projectOutDir, args[1]); err != nil {
+			return err
+		}
+		if b, err = runs.Load(projectOutDir, args[2]); err != nil {
+			return err
+		}
+	case 2:
+		var err error
+		if a, err = runs.Load(projectOutDir, args[1]); err != nil {
+			return err
+		}
+		if b, err = runs.Latest(projectOutDir); err != nil {
+			return err
+		}
+	default:
+		manifests, err := runs.List(projectOutDir)
+		if err != nil {
+			return err
+		}
+		if len(manifests) < 2 {
+			return fmt.Errorf("need at least two runs to diff, found %d", len(manifests))
+		}
+		a, b = manifests[len(manifests)-2], manifests[len(manifests)-1]
+	}
+
+	fmt.Printf("--- %s\n+++ %s\n\n", a.ID, b.ID)
+
+	if a.Backend != b.Backend || a.Model != b.Model {
+		fmt.Printf("backend: %s/%s → %s/%s\n", a.Backend, a.Model, b.Backend, b.Model)
+	}
+	for _, line := range diffConfig(a.Config, b.Config) {
+		fmt.Println(line)
+	}
+
+	before := resultsByFile(a.Results)
+	after := resultsByFile(b.Results)
+	files := make([]string, 0, len(before)+len(after))
+	for f := range before {
+		files = append(files, f)
+	}
+	for f := range after {
+		if _, ok := before[f]; !ok {
+			files = append(files, f)
+		}
+	}
+	sort.Strings(files)
+
+	changed := 0
+	for _, f := range files {
+		ra, inA := before[f]
+		rb, inB := after[f]
+		switch {
+		case !inA:
+			fmt.Printf("+ %-40s %s\n", f, resultStatus(rb))
+		case !inB:
+			fmt.Printf("- %-40s %s\n", f, resultStatus(ra))
+		case resultStatus(ra) != resultStatus(rb) || ra.Spec.Prompt != rb.Spec.Prompt:
+			note := ""
+			if ra.Spec.Prompt != rb.Spec.Prompt {
+				note = " (prompt changed)"
+			}
+			fmt.Printf("~ %-40s %s → %s%s\n", f, resultStatus(ra), resultStatus(rb), note)
+		default:
+			continue
+		}
+		changed++
+	}
+	if changed == 0 {
+		fmt.Println("No per-image differences")
+	}
+	return nil
+}
+
+func resultsByFile(results []generator.GenerationResult) map[string]generator.GenerationResult {
+	m := make(map[string]generator.GenerationResult, len(results))
+	for _, r := range results {
+		m[r.Spec.Filename] = r
+	}
+	return m
+}
+
+// diffConfig returns one line per top-level config field that differs
+func diffConfig(a, b *config.Project) []string {
+	fa, fb := configFields(a), configFields(b)
+
+	keys := make([]string, 0, len(fa)+len(fb))
+	for k := range fa {
+		keys = append(keys, k)
+	}
+	for k := range fb {
+		if _, ok := fa[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, k := range keys {
+		if !reflect.DeepEqual(fa[k], fb[k]) {
+			lines = append(lines, fmt.Sprintf("config %s: %v → %v", k, fa[k], fb[k]))
+		}
+	}
+	return lines
+}
+
+func configFields(p *config.Project) map[string]any {
+	fields := map[string]any{}
+	if p == nil {
+		return fields
+	}
+	data, _ := json.Marshal(p)
+	json.Unmarshal(data, &fields)
+	return fields
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rickhallett/beautifi/internal/api"
+	"github.com/rickhallett/beautifi/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// skipSettings is a flag annotation that keeps user settings and
+// environment variables from changing the flag's default
+const skipSettings = "beautifi_skip_settings"
+
+// setting links a config.yaml key to its environment variable and flag.
+// Values apply in order: flag, then environment, then config.yaml, then
+// the flag's built-in default.
+type setting struct {
+	key   string // config.yaml key
+	env   string
+	flag  string
+	value func(*config.Settings) string // "" when unset
+}
+
+var settings = []setting{
+	{"backend", "BEAUTIFI_BACKEND", "backend", func(s *config.Settings) string { return s.Backend }},
+	{"model", "BEAUTIFI_MODEL", "model", func(s *config.Settings) string { return s.Model }},
+	{"output_dir", "BEAUTIFI_OUTPUT_DIR", "output-dir", func(s *config.Settings) string { return expandHome(s.OutputDir) }},
+	{"variants", "BEAUTIFI_VARIANTS", "variants", func(s *config.Settings) string { return itoa(s.Variants) }},
+	{"parallel", "BEAUTIFI_PARALLEL", "parallel", func(s *config.Settings) string { return itoa(s.Parallel) }},
+	{"retries", "BEAUTIFI_RETRIES", "retries", func(s *config.Settings) string {
+		if s.Retries == nil {
+			return ""
+		}
+		return strconv.Itoa(*s.Retries)
+	}},
+	{"rpm", "BEAUTIFI_RPM", "rpm", func(s *config.Settings) string { return itoa(s.RPM) }},
+	{"max_inflight", "BEAUTIFI_MAX_INFLIGHT", "max-inflight", func(s *config.Settings) string { return itoa(s.MaxInFlight) }},
+	{"styles", "BEAUTIFI_STYLES", "styles", func(s *config.Settings) string { return strings.Join(s.Styles, ",") }},
+}
+
+// resolvedSetting is the effective value of a setting and where it came from
+type resolvedSetting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// resolveSettings computes the effective value of every setting. lookup
+// finds the flag a setting overrides; settings without a flag are still
+// resolved, for display.
+func resolveSettings(s *config.Settings, lookup func(name string) *pflag.Flag) []resolvedSetting {
+	var out []resolvedSetting
+	for _, st := range settings {
+		r := resolvedSetting{Key: st.key, Source: "default"}
+		f := lookup(st.flag)
+		if f != nil {
+			r.Value = strings.Trim(f.DefValue, "[]")
+		}
+
+		switch env, ok := os.LookupEnv(st.env); {
+		case f != nil && f.Changed:
+			r.Value, r.Source = strings.Trim(f.Value.String(), "[]"), "flag --"+st.flag
+		case ok && env != "":
+			r.Value, r.Source = env, "env "+st.env
+		case st.value(s) != "":
+			r.Value, r.Source = st.value(s), config.SettingsPath(cfgDir)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// userSettings is config.yaml as loaded by applySettings; nil if it has
+// not been loaded or is invalid
+var userSettings *config.Settings
+
+// applySettings sets the default of every flag of cmd that has a value
+// in the environment or config.yaml and was not given on the command line
+func applySettings(cmd *cobra.Command) error {
+	s, err := config.LoadSettings(cfgDir, api.Backends())
+	if err != nil {
+		return err
+	}
+	userSettings = s
+
+	for _, r := range resolveSettings(s, cmd.Flag) {
+		if r.Key == "backend" && r.Source != "default" && r.Value != "" && !slices.Contains(api.Backends(), r.Value) {
+			return fmt.Errorf("%s: unknown backend %q%s (available: %s)",
+				r.Source, r.Value, didYouMean(r.Value, api.Backends()), strings.Join(api.Backends(), ", "))
+		}
+		f := cmd.Flag(settingFlag(r.Key))
+		if f == nil || f.Changed || r.Source == "default" || f.Annotations[skipSettings] != nil {
+			continue
+		}
+		// Value.Set, not FlagSet.Set, so the flag still counts as unchanged
+		if err := f.Value.Set(r.Value); err != nil {
+			return fmt.Errorf("%s: invalid %s %q: %w", r.Source, r.Key, r.Value, err)
+		}
+	}
+	return nil
+}
+
+func settingFlag(key string) string {
+	for _, st := range settings {
+		if st.key == key {
+			return st.flag
+		}
+	}
+	return ""
+}
+
+func itoa(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~"); ok && (rest == "" || rest[0] == '/') {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, rest)
+	}
+	return path
+}
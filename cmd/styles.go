@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rickhallett/beautifi/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var stylesCmd = &cobra.Command{
+	Use:   "styles",
+	Short: "List, show and validate style presets",
+	Long: `Inspect the style presets available to projects.
+
+Presets come from the built-ins, ~/.config/beautifi/styles/*.yaml and a
+project's style_presets block, in increasing order of precedence.`,
+}
+
+var stylesListCmd = &cobra.Command{
+	Use:   "list [project]",
+	Short: "List style presets (including a project's own presets)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runStylesList,
+}
+
+var stylesShowCmd = &cobra.Command{
+	Use:   "show <name> [project]",
+	Short: "Show a style preset",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE:  runStylesShow,
+}
+
+var stylesValidateCmd = &cobra.Command{
+	Use:   "validate [project...]",
+	Short: "Check style files and the styles used by projects (default: all projects)",
+	RunE:  runStylesValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(stylesCmd)
+	stylesCmd.AddCommand(stylesListCmd, stylesShowCmd, stylesValidateCmd)
+}
+
+// loadStylesFor loads presets, including those of the named project if any
+func loadStylesFor(args []string) (map[string]config.StylePreset, error) {
+	var proj *config.Project
+	if len(args) > 0 {
+		cfgPath := filepath.Join(cfgDir, "projects", args[0]+".yaml")
+		p, err := config.LoadProject(cfgPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load project config: %w", err)
+		}
+		proj = p
+	}
+	return config.LoadStyles(cfgDir, proj)
+}
+
+func runStylesList(cmd *cobra.Command, args []string) error {
+	presets, err := loadStylesFor(args)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-18s  %-40s  %s\n", "STYLE", "DESCRIPTION", "SOURCE")
+	for _, name := range config.StyleNames(presets) {
+		p := presets[name]
+		fmt.Printf("%-18s  %-40s  %s\n", name, truncate(p.Description, 40), p.Source)
+	}
+	return nil
+}
+
+func runStylesShow(cmd *cobra.Command, args []string) error {
+	presets, err := loadStylesFor(args[1:])
+	if err != nil {
+		return err
+	}
+
+	p, ok := presets[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown style %q%s", args[0], didYouMean(args[0], config.StyleNames(presets)))
+	}
+
+	fmt.Printf("Style:       %s\n", args[0])
+	fmt.Printf("Source:      %s\n", p.Source)
+	if p.Description != "" {
+		fmt.Printf("Description: %s\n", p.Description)
+	}
+	fmt.Printf("Keywords:    %s\n", strings.Join(p.Keywords, ", "))
+	if len(p.NegativeKeywords) > 0 {
+		fmt.Printf("Negative:    %s\n", strings.Join(p.NegativeKeywords, ", "))
+	}
+	if p.PromptSuffix != "" {
+		fmt.Printf("Suffix:      %s\n", p.PromptSuffix)
+	}
+	return nil
+}
+
+func runStylesValidate(cmd *cobra.Command, args []string) error {
+	presets, err := config.LoadStyles(cfgDir, nil)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, name := range config.StyleNames(presets) {
+		if len(presets[name].Keywords) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: style %q has no keywords", presets[name].Source, name))
+		}
+	}
+
+	projects := args
+	if len(projects) == 0 {
+		files, _ := config.ProjectFiles(filepath.Join(cfgDir, "projects"))
+		for _, f := range files {
+			projects = append(projects, strings.TrimSuffix(filepath.Base(f), ".yaml"))
+		}
+	}
+
+	for _, name := range projects {
+		cfgPath := filepath.Join(cfgDir, "projects", name+".yaml")
+		proj, err := config.LoadProject(cfgPath)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", cfgPath, err))
+			continue
+		}
+		projPresets, err := config.LoadStyles(cfgDir, proj)
+		if err != nil {
+			return err
+		}
+		for _, p := range unknownStyles(proj, projPresets) {
+			problems = append(problems, cfgPath+": "+p.msg)
+		}
+	}
+
+	if len(problems) == 0 {
+		fmt.Printf("%d styles and %d projects OK\n", len(presets), len(projects))
+		return nil
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	cmd.SilenceUsage = true
+	return fmt.Errorf("%d problems found", len(problems))
+}
+
+// styleProblem is an unknown style at a key path of a project file
+type styleProblem struct {
+	key string // e.g. "styles.2" or "style_templates.glow"
+	msg string
+}
+
+// unknownStyles describes the styles and style_templates entries of proj
+// that have no preset
+func unknownStyles(proj *config.Project, presets map[string]config.StylePreset) []styleProblem {
+	known := config.StyleNames(presets)
+	var problems []styleProblem
+	for i, s := range proj.Styles {
+		if _, ok := presets[s]; !ok {
+			problems = append(problems, styleProblem{fmt.Sprintf("styles.%d", i), fmt.Sprintf("unknown style %q%s", s, didYouMean(s, known))})
+		}
+	}
+
+	var templated []string
+	for s := range proj.StyleTemplates {
+		templated = append(templated, s)
+	}
+	sort.Strings(templated)
+	for _, s := range templated {
+		if _, ok := presets[s]; !ok {
+			problems = append(problems, styleProblem{"style_templates." + s, fmt.Sprintf("style_templates: unknown style %q%s", s, didYouMean(s, known))})
+		}
+	}
+	return problems
+}
+
+// warnStyles prints warnings for unknown project styles and for --styles
+// values the project does not use, which filterStyles would drop. Values
+// from the styles setting are not warned about: other projects may use them.
+func warnStyles(proj *config.Project, presets map[string]config.StylePreset, requested []string, source string) {
+	for _, p := range unknownStyles(proj, presets) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", p.msg)
+	}
+
+	if source != "--styles" || len(requested) == 0 || requested[0] == "all" {
+		return
+	}
+	for _, s := range requested {
+		if !slices.Contains(proj.Styles, s) {
+			fmt.Fprintf(os.Stderr, "Warning: --styles %q is not one of the project's styles %v%s\n", s, proj.Styles, didYouMean(s, proj.Styles))
+		}
+	}
+}
+
+func didYouMean(name string, known []string) string {
+	if s := config.Suggest(name, known); s != "" {
+		return fmt.Sprintf(" (did you mean %q?)", s)
+	}
+	return ""
+}
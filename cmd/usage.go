@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rickhallett/beautifi/internal/ledger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageSince string
+	usageBy    string
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report API usage and estimated spend",
+	Long: `Summarise the usage ledger (~/.config/beautifi/usage.jsonl).
+
+Every API call is recorded with its project, backend, model, style, outcome,
+latency and estimated cost. Costs come from the built-in pricing table and
+are estimates to reconcile against your Google bill.`,
+	Args: cobra.NoArgs,
+	RunE: runUsage,
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+
+	usageCmd.Flags().StringVar(&usageSince, "since", "30d", "time window: duration (30d, 2w, 12h) or date (2006-01-02)")
+	usageCmd.Flags().StringVar(&usageBy, "by", "project", "group by ("+strings.Join(ledger.GroupKeys, ", ")+")")
+}
+
+func runUsage(cmd *cobra.Command, args []string) error {
+	since, err := parseSince(usageSince, time.Now())
+	if err != nil {
+		return err
+	}
+
+	usage := ledger.Open(cfgDir)
+	entries, err := usage.Read(since)
+	if err != nil {
+		return err
+	}
+	groups, err := ledger.Summarize(entries, usageBy)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Usage since %s (%s)\n\n", since.Format("2006-01-02 15:04"), usage.Path())
+	if len(entries) == 0 {
+		fmt.Println("No API calls recorded")
+		return nil
+	}
+
+	fmt.Printf("%-28s  %6s  %6s  %10s  %9s\n", strings.ToUpper(usageBy), "CALLS", "OK", "COST", "AVG TIME")
+	var total ledger.Group
+	for _, g := range groups {
+		fmt.Printf("%-28s  %6d  %6d  %10s  %9s\n",
+			truncate(g.Key, 28), g.Calls, g.Succeeded, fmt.Sprintf("$%.2f", g.CostUSD), g.AvgLatency().Round(100*time.Millisecond))
+		total.Calls += g.Calls
+		total.Succeeded += g.Succeeded
+		total.CostUSD += g.CostUSD
+		total.Latency += g.Latency
+	}
+	fmt.Printf("%-28s  %6d  %6d  %10s  %9s\n",
+		"TOTAL", total.Calls, total.Succeeded, fmt.Sprintf("$%.2f", total.CostUSD), total.AvgLatency().Round(100*time.Millisecond))
+
+	return nil
+}
+
+// parseSince accepts a look-back duration with d/w suffixes in addition to
+// Go durations, or an absolute date
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+
+	if n := len(s); n > 1 {
+		unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}[s[n-1]]
+		if unit > 0 {
+			count, err := strconv.Atoi(s[:n-1])
+			if err == nil && count >= 0 {
+				return now.Add(-time.Duration(count) * unit), nil
+			}
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid --since %q: use e.g. 30d, 2w, 12h or 2006-01-02", s)
+	}
+	return now.Add(-d), nil
+}
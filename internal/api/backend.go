@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// DefaultBackend is used when no backend is specified
+const DefaultBackend = "gemini"
+
+// ErrNoAPIKey is returned by backends that need an API key when none is given
+var ErrNoAPIKey = errors.New("no API key provided")
+
+// GenerationOptions holds per-request generation parameters. Zero values
+// leave the choice to the model.
+type GenerationOptions struct {
+	AspectRatio        string          `json:"aspect_ratio,omitempty"` // e.g., "1:1", "16:9"
+	ImageSize          string          `json:"image_size,omitempty"`   // "1K", "2K", "4K"
+	Temperature        *float32        `json:"temperature,omitempty"`
+	Seed               *int32          `json:"seed,omitempty"`
+	CandidateCount     int             `json:"candidate_count,omitempty"`
+	ResponseModalities []string        `json:"response_modalities,omitempty"` // e.g., ["TEXT", "IMAGE"]
+	SafetySettings     []SafetySetting `json:"safety_settings,omitempty"`
+
+	// Imagen predict parameters; ignored by backends without an equivalent
+	NegativePrompt     string `json:"negative_prompt,omitempty"`
+	PersonGeneration   string `json:"person_generation,omitempty"`   // dont_allow, allow_adult, allow_all
+	SafetyFilterLevel  string `json:"safety_filter_level,omitempty"` // block_low_and_above ... block_none
+	AddWatermark       *bool  `json:"add_watermark,omitempty"`
+	EnhancePrompt      *bool  `json:"enhance_prompt,omitempty"`
+	Language           string `json:"language,omitempty"`         // prompt language, e.g. "en", "auto"
+	OutputMimeType     string `json:"output_mime_type,omitempty"` // image/png, image/jpeg
+	CompressionQuality *int   `json:"compression_quality,omitempty"`
+	IncludeRaiReason   bool   `json:"include_rai_reason,omitempty"`
+}
+
+// SafetySetting sets the block threshold for one harm category, using the
+// API's enum names (e.g., HARM_CATEGORY_HARASSMENT, BLOCK_ONLY_HIGH)
+type SafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+// Image is one generated image
+type Image struct {
+	Data     []byte
+	MimeType string // as reported by the API, e.g. "image/png"
+	Text     string // text the model returned with the image, if any
+}
+
+// Capabilities describes what a backend supports
+type Capabilities struct {
+	// MaxImagesPerCall is the most images one request can return
+	MaxImagesPerCall int
+	// DefaultImagesPerCall is how many variants are requested together
+	// when the project does not set candidate_count
+	DefaultImagesPerCall int
+	// AspectRatios lists the accepted aspect ratios; empty means any
+	AspectRatios []string
+	// APIKeys names the keys the backend accepts, most specific first,
+	// e.g. "imagen" then the "gemini" key of the same account; empty if
+	// it needs none
+	APIKeys []string
+}
+
+// SupportsAspectRatio reports whether ratio is accepted. An empty ratio
+// leaves the choice to the model and is always accepted.
+func (c Capabilities) SupportsAspectRatio(ratio string) bool {
+	return ratio == "" || len(c.AspectRatios) == 0 || slices.Contains(c.AspectRatios, ratio)
+}
+
+// Backend is an image generation service
+type Backend interface {
+	// Name returns the registry name of the backend
+	Name() string
+	// Model returns the model the backend generates with
+	Model() string
+	// Capabilities reports what the backend supports
+	Capabilities() Capabilities
+	// Generate creates images from a text prompt. opts.CandidateCount
+	// requests several images in one call; fewer may be returned if some
+	// are filtered.
+	Generate(ctx context.Context, prompt string, opts GenerationOptions) ([]Image, error)
+	// Close releases any resources held by the backend
+	Close() error
+}
+
+// TextGenerator is implemented by backends that can also answer text
+// prompts, e.g. to suggest project settings
+type TextGenerator interface {
+	// GenerateText returns the model's reply to prompt. With jsonOutput
+	// the reply is requested as a JSON document.
+	GenerateText(ctx context.Context, prompt string, jsonOutput bool) (string, error)
+}
+
+// BackendFactory creates a backend from an API key
+type BackendFactory func(apiKey string) (Backend, error)
+
+type registration struct {
+	model   string
+	caps    Capabilities
+	factory BackendFactory
+}
+
+var backends = map[string]registration{}
+
+// RegisterBackend makes a backend available by name. model and caps are
+// the model the backend uses and what it supports, reported before a client
+// exists (e.g. for dry runs and config validation).
+func RegisterBackend(name, model string, caps Capabilities, factory BackendFactory) {
+	if _, exists := backends[name]; exists {
+		panic("api: backend registered twice: " + name)
+	}
+	backends[name] = registration{model: model, caps: caps, factory: factory}
+}
+
+// NewBackend creates the named backend
+func NewBackend(name, apiKey string) (Backend, error) {
+	reg, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q (available: %v)", name, Backends())
+	}
+	RegisterSecret(apiKey)
+	b, err := reg.factory(apiKey)
+	return b, RedactError(err)
+}
+
+// modelSetter is implemented by backends whose model can be chosen
+type modelSetter interface {
+	setModel(model string)
+}
+
+// NewBackendModel creates the named backend using model instead of the
+// backend's default. An empty model keeps the default.
+func NewBackendModel(name, apiKey, model string) (Backend, error) {
+	b, err := NewBackend(name, apiKey)
+	if err != nil || model == "" || model == b.Model() {
+		return b, err
+	}
+	setter, ok := b.(modelSetter)
+	if !ok {
+		b.Close()
+		return nil, fmt.Errorf("backend %q does not support choosing a model", name)
+	}
+	setter.setModel(model)
+	return b, nil
+}
+
+// BackendModel returns the model used by the named backend, or "" if the
+// backend is unknown
+func BackendModel(name string) string {
+	return backends[name].model
+}
+
+// BackendCapabilities returns what the named backend supports, or the zero
+// Capabilities if the backend is unknown
+func BackendCapabilities(name string) Capabilities {
+	return backends[name].caps
+}
+
+// Backends returns the names of all registered backends, sorted
+func Backends() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
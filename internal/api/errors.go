@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// ErrorKind classifies API failures so callers can decide how to react
+type ErrorKind int
+
+const (
+	KindUnknown ErrorKind = iota
+	KindRateLimited
+	KindQuota
+	KindAuth
+	KindSafetyBlocked
+	KindTransient
+	KindInvalidRequest
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindRateLimited:
+		return "rate limited"
+	case KindQuota:
+		return "quota exhausted"
+	case KindAuth:
+		return "authentication failed"
+	case KindSafetyBlocked:
+		return "blocked by safety filter"
+	case KindTransient:
+		return "transient error"
+	case KindInvalidRequest:
+		return "invalid request"
+	default:
+		return "api error"
+	}
+}
+
+// Error is a classified API failure
+type Error struct {
+	Kind       ErrorKind
+	StatusCode int           // HTTP status, 0 if not applicable
+	Message    string        // server or client supplied detail
+	RetryAfter time.Duration // server requested delay, 0 if none
+	Err        error         // underlying error, if any
+}
+
+func (e *Error) Error() string {
+	msg := e.Kind.String()
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" [%d]", e.StatusCode)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	} else if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return Redact(msg)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether repeating the request may succeed
+func (e *Error) Retryable() bool {
+	return e.Kind == KindRateLimited || e.Kind == KindTransient
+}
+
+// KindOf returns the classification of err, or KindUnknown
+func KindOf(err error) ErrorKind {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind
+	}
+	return KindUnknown
+}
+
+// IsRetryable reports whether err is a transient class worth retrying
+func IsRetryable(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Retryable()
+}
+
+// retryAfter returns the server requested delay carried by err, if any
+func retryAfter(err error) time.Duration {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// classifyStatus maps an HTTP status and server message to an Error
+func classifyStatus(code int, status, message string, retryAfter time.Duration) *Error {
+	e := &Error{StatusCode: code, Message: message, RetryAfter: retryAfter}
+	lower := strings.ToLower(status + " " + message)
+
+	switch {
+	case code == http.StatusTooManyRequests || status == "RESOURCE_EXHAUSTED":
+		if isQuotaMessage(lower) {
+			e.Kind = KindQuota
+		} else {
+			e.Kind = KindRateLimited
+		}
+	case code == http.StatusUnauthorized || code == http.StatusForbidden,
+		strings.Contains(lower, "api key not valid"), strings.Contains(lower, "api_key_invalid"):
+		e.Kind = KindAuth
+	case code == http.StatusRequestTimeout || code >= 500:
+		e.Kind = KindTransient
+	case code >= 400:
+		e.Kind = KindInvalidRequest
+	default:
+		e.Kind = KindUnknown
+	}
+	return e
+}
+
+// isQuotaMessage distinguishes hard quota exhaustion (daily limits, billing)
+// from per-minute rate limiting; both arrive as 429 RESOURCE_EXHAUSTED.
+func isQuotaMessage(lower string) bool {
+	for _, marker := range []string{"per day", "perday", "per_day", "billing", "limit: 0", "exceeded your current quota"} {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyError converts errors from the transport or SDK into Errors.
+// Cancellation of ctx is returned unchanged so it is never retried.
+func classifyError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return err
+	}
+
+	var genaiErr genai.APIError
+	if errors.As(err, &genaiErr) {
+		message := genaiErr.Message
+		if message == "" {
+			message = genaiErr.Status
+		}
+		e := classifyStatus(genaiErr.Code, genaiErr.Status, message+" "+fmt.Sprint(genaiErr.Details), retryDelayFromDetails(genaiErr.Details))
+		e.Message = message
+		e.Err = err
+		return e
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded) {
+		return &Error{Kind: KindTransient, Err: err}
+	}
+
+	return RedactError(err)
+}
+
+// parseRetryAfter reads an HTTP Retry-After header (seconds or HTTP date)
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// retryDelayFromDetails extracts google.rpc.RetryInfo.retryDelay ("30s")
+func retryDelayFromDetails(details []map[string]any) time.Duration {
+	for _, d := range details {
+		if t, _ := d["@type"].(string); !strings.HasSuffix(t, "google.rpc.RetryInfo") {
+			continue
+		}
+		if s, ok := d["retryDelay"].(string); ok {
+			if delay, err := time.ParseDuration(s); err == nil {
+				return delay
+			}
+		}
+	}
+	return 0
+}
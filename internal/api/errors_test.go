@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClassifyStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    int
+		status  string
+		message string
+		want    ErrorKind
+	}{
+		{"rate limited", 429, "RESOURCE_EXHAUSTED", "Resource has been exhausted (e.g. check quota).", KindRateLimited},
+		{"rate limited by status only", 0, "RESOURCE_EXHAUSTED", "", KindRateLimited},
+		{"daily quota", 429, "RESOURCE_EXHAUSTED", "Quota exceeded for metric: generate_requests_per_model_per_day", KindQuota},
+		{"billing quota", 429, "RESOURCE_EXHAUSTED", "You exceeded your current quota, please check your plan and billing details.", KindQuota},
+		{"zero limit", 429, "", "Quota exceeded, limit: 0", KindQuota},
+		{"unauthorized", 401, "UNAUTHENTICATED", "", KindAuth},
+		{"forbidden", 403, "PERMISSION_DENIED", "", KindAuth},
+		{"invalid key", 400, "INVALID_ARGUMENT", "API key not valid. Please pass a valid API key.", KindAuth},
+		{"server error", 500, "INTERNAL", "", KindTransient},
+		{"unavailable", 503, "UNAVAILABLE", "The model is overloaded.", KindTransient},
+		{"timeout", 408, "", "", KindTransient},
+		{"bad request", 400, "INVALID_ARGUMENT", "Unsupported aspect ratio", KindInvalidRequest},
+		{"not found", 404, "NOT_FOUND", "model not found", KindInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := classifyStatus(tt.code, tt.status, tt.message, 0)
+			if e.Kind != tt.want {
+				t.Errorf("classifyStatus(%d, %q, %q) = %v, want %v", tt.code, tt.status, tt.message, e.Kind, tt.want)
+			}
+			if retryable := tt.want == KindRateLimited || tt.want == KindTransient; IsRetryable(e) != retryable {
+				t.Errorf("IsRetryable = %v, want %v", IsRetryable(e), retryable)
+			}
+		})
+	}
+}
+
+func TestClassifyErrorKeepsCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := classifyError(ctx, errors.New("request aborted"))
+	if !errors.Is(err, context.Canceled) || IsRetryable(err) {
+		t.Errorf("classifyError after cancel = %v, want context.Canceled", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("30"); got != 30*time.Second {
+		t.Errorf("parseRetryAfter(30) = %v", got)
+	}
+	if got := parseRetryAfter(""); got != 0 {
+		t.Errorf("parseRetryAfter(\"\") = %v", got)
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("parseRetryAfter(%q) = %v, want about an hour", date, got)
+	}
+}
+
+func TestRetryDelayFromDetails(t *testing.T) {
+	details := []map[string]any{
+		{"@type": "type.googleapis.com/google.rpc.QuotaFailure"},
+		{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "17s"},
+	}
+	if got := retryDelayFromDetails(details); got != 17*time.Second {
+		t.Errorf("retryDelayFromDetails = %v, want 17s", got)
+	}
+}
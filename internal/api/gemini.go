@@ -3,7 +3,8 @@ package api
 import (
 	"context"
 	"fmt"
-	"os"
+	"reflect"
+	"strings"
 
 	"google.golang.org/genai"
 )
@@ -11,18 +12,43 @@ import (
 const (
 	// Gemini image generation model
 	ImageModel = "gemini-3-pro-image-preview"
+
+	// TextModel answers text prompts (see GenerateText)
+	TextModel = "gemini-2.5-flash"
+
+	// geminiMaxCandidates is the API limit on candidateCount
+	geminiMaxCandidates = 8
 )
 
+// geminiCapabilities describes the Gemini image model
+var geminiCapabilities = Capabilities{
+	// Candidates are opt-in via candidate_count: not every image model
+	// honours candidateCount, and one candidate per call keeps seeds exact.
+	MaxImagesPerCall:     geminiMaxCandidates,
+	DefaultImagesPerCall: 1,
+	AspectRatios:         []string{"1:1", "2:3", "3:2", "3:4", "4:3", "4:5", "5:4", "9:16", "16:9", "21:9"},
+	APIKeys:              []string{"gemini"},
+}
+
 // GeminiClient handles communication with Google's Gemini API
 type GeminiClient struct {
 	client *genai.Client
 	model  string
 }
 
+func init() {
+	RegisterBackend("gemini", ImageModel, geminiCapabilities, func(apiKey string) (Backend, error) {
+		return NewGeminiClient(apiKey)
+	})
+}
+
 // NewGeminiClient creates a new Gemini API client
 func NewGeminiClient(apiKey string) (*GeminiClient, error) {
+	if apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
 	ctx := context.Background()
-	
+
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
 		APIKey: apiKey,
 	})
@@ -36,8 +62,64 @@ func NewGeminiClient(apiKey string) (*GeminiClient, error) {
 	}, nil
 }
 
-// GenerateImage generates an image from a prompt
-func (c *GeminiClient) GenerateImage(ctx context.Context, prompt string) ([]byte, error) {
+// Name implements Backend
+func (c *GeminiClient) Name() string {
+	return "gemini"
+}
+
+// Model implements Backend
+func (c *GeminiClient) Model() string {
+	return c.model
+}
+
+func (c *GeminiClient) setModel(model string) {
+	c.model = model
+}
+
+// Capabilities implements Backend
+func (c *GeminiClient) Capabilities() Capabilities {
+	return geminiCapabilities
+}
+
+// Generate implements Backend. Each image part of each candidate in the
+// response becomes one Image, carrying any text the model returned
+// alongside it.
+func (c *GeminiClient) Generate(ctx context.Context, prompt string, opts GenerationOptions) ([]Image, error) {
+	return c.generateImages(ctx, prompt, opts)
+}
+
+// contentConfig converts options into a genai request config, or nil when
+// every option is left to the model
+func contentConfig(opts GenerationOptions) *genai.GenerateContentConfig {
+	cfg := &genai.GenerateContentConfig{
+		Temperature:        opts.Temperature,
+		Seed:               opts.Seed,
+		CandidateCount:     int32(opts.CandidateCount),
+		ResponseModalities: opts.ResponseModalities,
+	}
+	if opts.AspectRatio != "" || opts.ImageSize != "" {
+		cfg.ImageConfig = &genai.ImageConfig{
+			AspectRatio: opts.AspectRatio,
+			ImageSize:   opts.ImageSize,
+		}
+	}
+	for _, s := range opts.SafetySettings {
+		cfg.SafetySettings = append(cfg.SafetySettings, &genai.SafetySetting{
+			Category:  genai.HarmCategory(s.Category),
+			Threshold: genai.HarmBlockThreshold(s.Threshold),
+		})
+	}
+
+	if reflect.ValueOf(*cfg).IsZero() {
+		return nil
+	}
+	return cfg
+}
+
+// generateImages requests images for a prompt and returns every image
+// part of every candidate. Candidates that were filtered are dropped; an
+// error is only returned when no candidate produced an image.
+func (c *GeminiClient) generateImages(ctx context.Context, prompt string, opts GenerationOptions) ([]Image, error) {
 	// Create content with text prompt
 	parts := []*genai.Part{
 		{Text: prompt},
@@ -47,38 +129,95 @@ func (c *GeminiClient) GenerateImage(ctx context.Context, prompt string) ([]byte
 	}
 
 	// Generate content
-	result, err := c.client.Models.GenerateContent(ctx, c.model, contents, nil)
+	result, err := c.client.Models.GenerateContent(ctx, c.model, contents, contentConfig(opts))
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate content: %w", err)
+		return nil, fmt.Errorf("failed to generate content: %w", classifyError(ctx, err))
 	}
 
-	// Extract image from response
+	// Extract images from response
+	if fb := result.PromptFeedback; fb != nil && fb.BlockReason != "" {
+		return nil, &Error{Kind: KindSafetyBlocked, Message: fmt.Sprintf("prompt blocked: %s %s", fb.BlockReason, fb.BlockReasonMessage)}
+	}
 	if len(result.Candidates) == 0 {
 		return nil, fmt.Errorf("no candidates in response")
 	}
 
-	for _, part := range result.Candidates[0].Content.Parts {
-		if part.InlineData != nil {
-			return part.InlineData.Data, nil
+	var (
+		images  []Image
+		blocked genai.FinishReason
+	)
+	for _, candidate := range result.Candidates {
+		if imgs := candidateImages(candidate); len(imgs) > 0 {
+			images = append(images, imgs...)
+		} else if safetyFinishReasons[candidate.FinishReason] {
+			blocked = candidate.FinishReason
 		}
 	}
 
-	return nil, fmt.Errorf("no image data in response")
+	if len(images) == 0 {
+		if blocked != "" {
+			return nil, &Error{Kind: KindSafetyBlocked, Message: fmt.Sprintf("generation stopped: %s", blocked)}
+		}
+		return nil, fmt.Errorf("no image data in response")
+	}
+	return images, nil
 }
 
-// GenerateImages generates multiple images from a prompt
-func (c *GeminiClient) GenerateImages(ctx context.Context, prompt string, count int) ([][]byte, error) {
-	var images [][]byte
-	
-	for i := 0; i < count; i++ {
-		img, err := c.GenerateImage(ctx, prompt)
-		if err != nil {
-			return images, fmt.Errorf("failed to generate image %d: %w", i+1, err)
+// candidateImages returns every image in a candidate, each carrying the
+// candidate's text parts, excluding the model's thoughts
+func candidateImages(candidate *genai.Candidate) []Image {
+	if candidate.Content == nil {
+		return nil
+	}
+
+	var (
+		images []Image
+		text   []string
+	)
+	for _, part := range candidate.Content.Parts {
+		switch {
+		case part.InlineData != nil && !part.Thought:
+			images = append(images, Image{Data: part.InlineData.Data, MimeType: part.InlineData.MIMEType})
+		case part.Text != "" && !part.Thought:
+			text = append(text, strings.TrimSpace(part.Text))
 		}
-		images = append(images, img)
 	}
-	
-	return images, nil
+	for i := range images {
+		images[i].Text = strings.Join(text, "\n")
+	}
+	return images
+}
+
+// GenerateText implements TextGenerator using TextModel
+func (c *GeminiClient) GenerateText(ctx context.Context, prompt string, jsonOutput bool) (string, error) {
+	var cfg *genai.GenerateContentConfig
+	if jsonOutput {
+		cfg = &genai.GenerateContentConfig{ResponseMIMEType: "application/json"}
+	}
+
+	result, err := c.client.Models.GenerateContent(ctx, TextModel, genai.Text(prompt), cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate text: %w", classifyError(ctx, err))
+	}
+	if fb := result.PromptFeedback; fb != nil && fb.BlockReason != "" {
+		return "", &Error{Kind: KindSafetyBlocked, Message: fmt.Sprintf("prompt blocked: %s %s", fb.BlockReason, fb.BlockReasonMessage)}
+	}
+
+	text := result.Text()
+	if text == "" {
+		return "", fmt.Errorf("no text in response")
+	}
+	return text, nil
+}
+
+// safetyFinishReasons are finish reasons that mean the output was filtered
+var safetyFinishReasons = map[genai.FinishReason]bool{
+	genai.FinishReasonSafety:                 true,
+	genai.FinishReasonProhibitedContent:      true,
+	genai.FinishReasonBlocklist:              true,
+	genai.FinishReasonSPII:                   true,
+	genai.FinishReasonImageSafety:            true,
+	genai.FinishReasonImageProhibitedContent: true,
 }
 
 // Close is a no-op for the genai client (it doesn't have a Close method)
@@ -86,8 +225,3 @@ func (c *GeminiClient) Close() error {
 	// genai.Client doesn't require explicit closing
 	return nil
 }
-
-// GetAPIKey returns the API key from environment
-func GetAPIKey() string {
-	return os.Getenv("GEMINI_API_KEY")
-}
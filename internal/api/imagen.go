@@ -2,41 +2,64 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
 const (
-	// Imagen 3 API endpoint
-	imagenEndpoint = "https://generativelanguage.googleapis.com/v1beta/models/imagen-3.0-generate-001:predict"
+	// Imagen 3 model and API endpoint
+	ImagenModel   = "imagen-3.0-generate-001"
+	imagenBaseURL = "https://generativelanguage.googleapis.com/v1beta/models/"
 )
 
 // ImagenClient handles communication with Google's Imagen API
 type ImagenClient struct {
 	apiKey     string
+	model      string
 	httpClient *http.Client
 }
 
 // ImagenRequest represents the API request structure
 type ImagenRequest struct {
-	Instances  []ImagenInstance  `json:"instances"`
-	Parameters ImagenParameters  `json:"parameters"`
+	Instances  []ImagenInstance `json:"instances"`
+	Parameters ImagenParameters `json:"parameters"`
 }
 
 type ImagenInstance struct {
 	Prompt string `json:"prompt"`
 }
 
+// ImagenParameters are the documented predict parameters
 type ImagenParameters struct {
-	SampleCount  int    `json:"sampleCount"`
-	AspectRatio  string `json:"aspectRatio,omitempty"`
-	OutputFormat string `json:"outputFormat,omitempty"`
+	SampleCount      int                  `json:"sampleCount"`
+	AspectRatio      string               `json:"aspectRatio,omitempty"`
+	NegativePrompt   string               `json:"negativePrompt,omitempty"`
+	PersonGeneration string               `json:"personGeneration,omitempty"`
+	SafetySetting    string               `json:"safetySetting,omitempty"`
+	AddWatermark     *bool                `json:"addWatermark,omitempty"`
+	EnhancePrompt    *bool                `json:"enhancePrompt,omitempty"`
+	Seed             *int32               `json:"seed,omitempty"` // requires addWatermark: false
+	Language         string               `json:"language,omitempty"`
+	SampleImageSize  string               `json:"sampleImageSize,omitempty"`
+	IncludeRaiReason bool                 `json:"includeRaiReason,omitempty"`
+	OutputOptions    *ImagenOutputOptions `json:"outputOptions,omitempty"`
 }
 
+// ImagenOutputOptions selects the encoding of returned images
+type ImagenOutputOptions struct {
+	MimeType           string `json:"mimeType,omitempty"`
+	CompressionQuality *int   `json:"compressionQuality,omitempty"` // JPEG only, 0-100
+}
+
+// imagenMaxSamples is the most images one predict call can return
+const imagenMaxSamples = 4
+
 // ImagenResponse represents the API response
 type ImagenResponse struct {
 	Predictions []ImagenPrediction `json:"predictions"`
@@ -46,6 +69,7 @@ type ImagenResponse struct {
 type ImagenPrediction struct {
 	BytesBase64Encoded string `json:"bytesBase64Encoded"`
 	MimeType           string `json:"mimeType"`
+	RaiFilteredReason  string `json:"raiFilteredReason,omitempty"` // set when this sample was filtered
 }
 
 type ImagenError struct {
@@ -54,31 +78,106 @@ type ImagenError struct {
 	Status  string `json:"status"`
 }
 
+// imagenCapabilities describes the Imagen predict endpoint
+var imagenCapabilities = Capabilities{
+	MaxImagesPerCall:     imagenMaxSamples,
+	DefaultImagesPerCall: imagenMaxSamples,
+	AspectRatios:         []string{"1:1", "3:4", "4:3", "9:16", "16:9"},
+	// Imagen is served by the Gemini API and takes the same key
+	APIKeys: []string{"imagen", "gemini"},
+}
+
+func init() {
+	RegisterBackend("imagen", ImagenModel, imagenCapabilities, func(apiKey string) (Backend, error) {
+		return NewImagenClient(apiKey)
+	})
+}
+
 // NewImagenClient creates a new Imagen API client
-func NewImagenClient(apiKey string) *ImagenClient {
+func NewImagenClient(apiKey string) (*ImagenClient, error) {
+	if apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
 	return &ImagenClient{
 		apiKey: apiKey,
+		model:  ImagenModel,
 		httpClient: &http.Client{
 			Timeout: 60 * time.Second,
 		},
+	}, nil
+}
+
+// Name implements Backend
+func (c *ImagenClient) Name() string {
+	return "imagen"
+}
+
+// Model implements Backend
+func (c *ImagenClient) Model() string {
+	return c.model
+}
+
+func (c *ImagenClient) setModel(model string) {
+	c.model = model
+}
+
+// Capabilities implements Backend
+func (c *ImagenClient) Capabilities() Capabilities {
+	return imagenCapabilities
+}
+
+// Generate implements Backend
+func (c *ImagenClient) Generate(ctx context.Context, prompt string, opts GenerationOptions) ([]Image, error) {
+	return c.GenerateWithOptions(ctx, prompt, opts)
+}
+
+// imagenParameters maps generation options onto predict parameters
+func imagenParameters(opts GenerationOptions) ImagenParameters {
+	count := opts.CandidateCount
+	if count < 1 {
+		count = 1
+	}
+	aspectRatio := opts.AspectRatio
+	if aspectRatio == "" {
+		aspectRatio = "1:1"
 	}
+
+	params := ImagenParameters{
+		SampleCount:      count,
+		AspectRatio:      aspectRatio,
+		NegativePrompt:   opts.NegativePrompt,
+		PersonGeneration: opts.PersonGeneration,
+		SafetySetting:    opts.SafetyFilterLevel,
+		AddWatermark:     opts.AddWatermark,
+		EnhancePrompt:    opts.EnhancePrompt,
+		Seed:             opts.Seed,
+		Language:         opts.Language,
+		SampleImageSize:  opts.ImageSize,
+		IncludeRaiReason: opts.IncludeRaiReason,
+	}
+	if opts.OutputMimeType != "" || opts.CompressionQuality != nil {
+		params.OutputOptions = &ImagenOutputOptions{
+			MimeType:           opts.OutputMimeType,
+			CompressionQuality: opts.CompressionQuality,
+		}
+	}
+	return params
 }
 
-// Generate creates an image from a text prompt
-func (c *ImagenClient) Generate(prompt string) ([]byte, error) {
-	return c.GenerateWithOptions(prompt, 1, "1:1")
+// Close is a no-op; the HTTP client holds no resources that need releasing
+func (c *ImagenClient) Close() error {
+	return nil
 }
 
-// GenerateWithOptions creates an image with custom parameters
-func (c *ImagenClient) GenerateWithOptions(prompt string, count int, aspectRatio string) ([]byte, error) {
+// GenerateWithOptions creates opts.CandidateCount images (sampleCount)
+// with custom parameters. Every returned prediction becomes an image;
+// filtered samples are dropped.
+func (c *ImagenClient) GenerateWithOptions(ctx context.Context, prompt string, opts GenerationOptions) ([]Image, error) {
 	reqBody := ImagenRequest{
 		Instances: []ImagenInstance{
 			{Prompt: prompt},
 		},
-		Parameters: ImagenParameters{
-			SampleCount: count,
-			AspectRatio: aspectRatio,
-		},
+		Parameters: imagenParameters(opts),
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -86,31 +185,34 @@ func (c *ImagenClient) GenerateWithOptions(prompt string, count int, aspectRatio
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	url := fmt.Sprintf("%s?key=%s", imagenEndpoint, c.apiKey)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	// The key goes in a header: URLs end up in error messages and logs
+	url := imagenBaseURL + c.model + ":predict"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, fmt.Errorf("create request: %w", RedactError(err))
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", c.apiKey)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("api request: %w", err)
+		return nil, fmt.Errorf("api request: %w", classifyError(ctx, err))
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
+		return nil, fmt.Errorf("read response: %w", classifyError(ctx, err))
 	}
 
 	if resp.StatusCode != http.StatusOK {
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
 		var errResp ImagenResponse
 		if json.Unmarshal(body, &errResp) == nil && errResp.Error != nil {
-			return nil, fmt.Errorf("api error [%d]: %s", errResp.Error.Code, errResp.Error.Message)
+			return nil, classifyStatus(resp.StatusCode, errResp.Error.Status, errResp.Error.Message, retryAfter)
 		}
-		return nil, fmt.Errorf("api error [%d]: %s", resp.StatusCode, string(body))
+		return nil, classifyStatus(resp.StatusCode, "", string(body), retryAfter)
 	}
 
 	var imgResp ImagenResponse
@@ -118,38 +220,36 @@ func (c *ImagenClient) GenerateWithOptions(prompt string, count int, aspectRatio
 		return nil, fmt.Errorf("parse response: %w", err)
 	}
 
-	if len(imgResp.Predictions) == 0 {
-		return nil, fmt.Errorf("no images returned from API")
+	// Decode base64 images
+	var images []Image
+	var filtered []string
+	for _, pred := range imgResp.Predictions {
+		if pred.BytesBase64Encoded == "" {
+			if pred.RaiFilteredReason != "" {
+				filtered = append(filtered, pred.RaiFilteredReason)
+			}
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(pred.BytesBase64Encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decode image: %w", err)
+		}
+		mimeType := pred.MimeType
+		if mimeType == "" {
+			mimeType = "image/png"
+		}
+		images = append(images, Image{Data: data, MimeType: mimeType})
 	}
 
-	// Decode base64 image
-	imageData, err := base64.StdEncoding.DecodeString(imgResp.Predictions[0].BytesBase64Encoded)
-	if err != nil {
-		return nil, fmt.Errorf("decode image: %w", err)
+	if len(images) == 0 {
+		// Imagen drops filtered images from the response rather than
+		// reporting an error, so an empty result means a safety block.
+		msg := "no images returned from API"
+		if len(filtered) > 0 {
+			msg += ": " + strings.Join(filtered, "; ")
+		}
+		return nil, &Error{Kind: KindSafetyBlocked, Message: msg}
 	}
 
-	return imageData, nil
-}
-
-// Stub mode for testing without API
-type StubClient struct{}
-
-func NewStubClient() *StubClient {
-	return &StubClient{}
-}
-
-func (c *StubClient) Generate(prompt string) ([]byte, error) {
-	// Return a small valid PNG (1x1 transparent pixel)
-	pngData := []byte{
-		0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a,
-		0x00, 0x00, 0x00, 0x0d, 0x49, 0x48, 0x44, 0x52,
-		0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01,
-		0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0x15, 0xc4,
-		0x89, 0x00, 0x00, 0x00, 0x0a, 0x49, 0x44, 0x41,
-		0x54, 0x08, 0xd7, 0x63, 0x00, 0x01, 0x00, 0x00,
-		0x05, 0x00, 0x01, 0x0d, 0x0a, 0x2d, 0xb4, 0x00,
-		0x00, 0x00, 0x00, 0x49, 0x45, 0x4e, 0x44, 0xae,
-		0x42, 0x60, 0x82,
-	}
-	return pngData, nil
+	return images, nil
 }
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimit configures client-side throttling. Zero values mean unlimited.
+type RateLimit struct {
+	RequestsPerMinute int `json:"requests_per_minute" yaml:"requests_per_minute"`
+	MaxInFlight       int `json:"max_in_flight" yaml:"max_in_flight"`
+}
+
+// defaultRateLimits are conservative per-model defaults that fit the
+// lowest paid tier; raise them with --rpm / --max-inflight if your
+// project has a higher quota.
+var defaultRateLimits = map[string]RateLimit{
+	ImageModel:  {RequestsPerMinute: 10, MaxInFlight: 4},
+	ImagenModel: {RequestsPerMinute: 20, MaxInFlight: 4},
+	StubModel:   {},
+}
+
+// DefaultRateLimit returns the default limit for a model
+func DefaultRateLimit(model string) RateLimit {
+	if limit, ok := defaultRateLimits[model]; ok {
+		return limit
+	}
+	return RateLimit{RequestsPerMinute: 10, MaxInFlight: 4}
+}
+
+// Limiter is a token bucket combined with a cap on in-flight requests.
+// Requests are paced evenly (one token, refilled at the per-minute rate)
+// rather than allowed to burst, because server quotas are enforced over
+// sliding windows.
+type Limiter struct {
+	limit RateLimit
+
+	mu   sync.Mutex
+	next time.Time // earliest time the next request may start
+
+	inFlight chan struct{}
+}
+
+// NewLimiter creates a limiter enforcing limit
+func NewLimiter(limit RateLimit) *Limiter {
+	l := &Limiter{limit: limit}
+	if limit.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, limit.MaxInFlight)
+	}
+	return l
+}
+
+// Limit returns the configured limit
+func (l *Limiter) Limit() RateLimit {
+	return l.limit
+}
+
+// Acquire blocks until a request may start. The returned release func
+// must be called when the request finishes.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release = func() {
+		if l.inFlight != nil {
+			<-l.inFlight
+		}
+	}
+
+	if l.limit.RequestsPerMinute > 0 {
+		if err := l.wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// wait reserves the next start slot and sleeps until it arrives
+func (l *Limiter) wait(ctx context.Context) error {
+	interval := time.Minute / time.Duration(l.limit.RequestsPerMinute)
+
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(interval)
+	l.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Hand the slot back if nobody has reserved after us
+		l.mu.Lock()
+		if l.next.Equal(start.Add(interval)) {
+			l.next = start
+		}
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+var (
+	sharedMu       sync.Mutex
+	sharedLimiters = map[string]*Limiter{}
+)
+
+// SharedLimiter returns the process-wide limiter for a backend and model,
+// creating it with limit on first use. Every worker and project in one
+// process that talks to the same model draws from the same limiter.
+func SharedLimiter(backend, model string, limit RateLimit) *Limiter {
+	key := backend + "/" + model
+
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	if l, ok := sharedLimiters[key]; ok {
+		return l
+	}
+	l := NewLimiter(limit)
+	sharedLimiters[key] = l
+	return l
+}
+
+// WithRateLimit wraps a backend so every request passes through l
+func WithRateLimit(b Backend, l *Limiter) Backend {
+	if l == nil || (l.limit.RequestsPerMinute <= 0 && l.limit.MaxInFlight <= 0) {
+		return b
+	}
+	return &limitedBackend{Backend: b, limiter: l}
+}
+
+type limitedBackend struct {
+	Backend
+	limiter *Limiter
+}
+
+func (lb *limitedBackend) Generate(ctx context.Context, prompt string, opts GenerationOptions) ([]Image, error) {
+	release, err := lb.limiter.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return lb.Backend.Generate(ctx, prompt, opts)
+}
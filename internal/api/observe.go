@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+type labelsKey struct{}
+
+// WithLabels returns a context carrying key/value labels that observers
+// receive with each call, e.g. WithLabels(ctx, "style", "neon-glow")
+func WithLabels(ctx context.Context, kv ...string) context.Context {
+	merged := map[string]string{}
+	for k, v := range Labels(ctx) {
+		merged[k] = v
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		merged[kv[i]] = kv[i+1]
+	}
+	return context.WithValue(ctx, labelsKey{}, merged)
+}
+
+// Labels returns the labels attached to ctx
+func Labels(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(labelsKey{}).(map[string]string)
+	return labels
+}
+
+// Call describes a single request made to a backend
+type Call struct {
+	Backend string
+	Model   string
+	Labels  map[string]string
+	Started time.Time
+	Latency time.Duration
+	Images  int // images returned
+	Err     error
+}
+
+// WithObserver wraps a backend so observe is called after every request.
+// Wrap the raw backend, inside retry, to see each individual attempt.
+func WithObserver(b Backend, observe func(Call)) Backend {
+	if observe == nil {
+		return b
+	}
+	return &observedBackend{Backend: b, observe: observe}
+}
+
+type observedBackend struct {
+	Backend
+	observe func(Call)
+}
+
+func (o *observedBackend) Generate(ctx context.Context, prompt string, opts GenerationOptions) ([]Image, error) {
+	start := time.Now()
+	images, err := o.Backend.Generate(ctx, prompt, opts)
+	o.observe(Call{
+		Backend: o.Name(),
+		Model:   o.Model(),
+		Labels:  Labels(ctx),
+		Started: start,
+		Latency: time.Since(start),
+		Images:  len(images),
+		Err:     err,
+	})
+	return images, err
+}
@@ -0,0 +1,26 @@
+package api
+
+import "time"
+
+// ModelPricing describes what one generated image costs and how long it
+// typically takes, used for estimates before history is available
+type ModelPricing struct {
+	PerImage       float64       // USD per generated image
+	TypicalLatency time.Duration // wall time of one request
+}
+
+// pricing is keyed by "<backend>/<model>". Prices are list prices in USD
+// at the time of writing; check the current Google pricing page before
+// relying on them for anything but budgeting.
+var pricing = map[string]ModelPricing{
+	"gemini/" + ImageModel:  {PerImage: 0.134, TypicalLatency: 20 * time.Second},
+	"imagen/" + ImagenModel: {PerImage: 0.03, TypicalLatency: 8 * time.Second},
+	"stub/" + StubModel:     {PerImage: 0, TypicalLatency: 0},
+}
+
+// Pricing returns the pricing for a backend and model. ok is false when
+// the model is not in the table.
+func Pricing(backend, model string) (p ModelPricing, ok bool) {
+	p, ok = pricing[backend+"/"+model]
+	return p, ok
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// redacted replaces secrets in errors, logs, metadata and manifests
+const redacted = "[REDACTED]"
+
+// minSecretLen keeps short values from redacting common substrings
+const minSecretLen = 8
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// keyPatterns match key material even when it was never registered:
+// key=... query parameters, API key headers and Google API keys
+var keyPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`([?&](?:api_?)?key=)[^&\s"']+`),
+	regexp.MustCompile(`(?i)(x-goog-api-key:\s*)\S+`),
+	regexp.MustCompile(`AIza[0-9A-Za-z_-]{35}`),
+}
+
+// RegisterSecret adds a value that Redact removes. NewBackend registers
+// every API key it is given.
+func RegisterSecret(secret string) {
+	secret = strings.TrimSpace(secret)
+	if len(secret) < minSecretLen {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, s := range secrets {
+		if s == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+}
+
+// Redact removes registered secrets and anything that looks like an API
+// key from s
+func Redact(s string) string {
+	secretsMu.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	secretsMu.RUnlock()
+
+	for _, p := range keyPatterns {
+		if p.NumSubexp() > 0 {
+			s = p.ReplaceAllString(s, "${1}"+redacted)
+		} else {
+			s = p.ReplaceAllString(s, redacted)
+		}
+	}
+	return s
+}
+
+// redactedError redacts the message of the error it wraps
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return Redact(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// RedactError wraps err so its message is redacted. errors.Is and
+// errors.As still see the original error. Only an err that is already
+// redacted at the top is returned as is: a redacted error further down
+// the chain says nothing about the text wrapped around it.
+func RedactError(err error) error {
+	if _, ok := err.(*redactedError); err == nil || ok {
+		return err
+	}
+	return &redactedError{err: err}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	const secret = "registered-secret-0123"
+	RegisterSecret(secret)
+	googleKey := "AIza" + strings.Repeat("x1_-", 8) + "abc"
+
+	tests := []struct {
+		name, in, leak string
+	}{
+		{"registered secret", "request failed for " + secret, secret},
+		{"query parameter", "GET https://example.com/v1/models?key=plainkey123&alt=json", "plainkey123"},
+		{"api_key parameter", "GET https://example.com/v1/models?alt=json&api_key=plainkey456", "plainkey456"},
+		{"header", "sent X-Goog-Api-Key: headerkey789 to the API", "headerkey789"},
+		{"google key", "invalid key " + googleKey + " supplied", googleKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Redact(tt.in)
+			if strings.Contains(got, tt.leak) {
+				t.Errorf("Redact(%q) = %q, still contains %q", tt.in, got, tt.leak)
+			}
+			if !strings.Contains(got, redacted) {
+				t.Errorf("Redact(%q) = %q, want %s marker", tt.in, got, redacted)
+			}
+		})
+	}
+}
+
+func TestRedactKeepsOrdinaryText(t *testing.T) {
+	RegisterSecret("short") // below minSecretLen, never registered
+	in := "a short prompt with no keys"
+	if got := Redact(in); got != in {
+		t.Errorf("Redact(%q) = %q, want it unchanged", in, got)
+	}
+}
+
+func TestErrorRedactsMessage(t *testing.T) {
+	const secret = "error-message-secret-4567"
+	RegisterSecret(secret)
+
+	err := &Error{Kind: KindAuth, StatusCode: 403, Message: "API key " + secret + " is invalid"}
+	if got := err.Error(); strings.Contains(got, secret) {
+		t.Errorf("Error() = %q, still contains the key", got)
+	}
+
+	err = &Error{Kind: KindTransient, Err: fmt.Errorf("dial https://example.com/?key=%s", secret)}
+	if got := err.Error(); strings.Contains(got, secret) {
+		t.Errorf("Error() = %q, still contains the key", got)
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	const secret = "wrapped-error-secret-8901"
+	RegisterSecret(secret)
+
+	if RedactError(nil) != nil {
+		t.Fatal("RedactError(nil) is not nil")
+	}
+
+	base := errors.New("bad key " + secret)
+	err := RedactError(base)
+	if got := err.Error(); strings.Contains(got, secret) {
+		t.Errorf("Error() = %q, still contains the key", got)
+	}
+	if !errors.Is(err, base) {
+		t.Error("errors.Is does not find the original error")
+	}
+	if RedactError(err) != err {
+		t.Error("RedactError wrapped an already redacted error again")
+	}
+
+	// Text wrapped around a redacted error must be redacted too
+	outer := RedactError(fmt.Errorf("retrying with %s: %w", secret, err))
+	if got := outer.Error(); strings.Contains(got, secret) {
+		t.Errorf("Error() = %q, still contains the key", got)
+	}
+	if !errors.Is(outer, base) {
+		t.Error("errors.Is does not find the original error through the outer wrap")
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first
+	BaseDelay   time.Duration // delay before the first retry
+	MaxDelay    time.Duration // cap on backoff and on honoured Retry-After
+}
+
+// DefaultRetryPolicy returns the policy used by the CLI
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   2 * time.Second,
+		MaxDelay:    60 * time.Second,
+	}
+}
+
+// backoff returns the jittered delay before retry number attempt (1-based)
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	// Equal jitter: half fixed, half random, so workers that failed
+	// together do not retry together.
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// WithRetry wraps a backend so rate-limited and transient failures are
+// retried with exponential backoff. Other error kinds fail immediately.
+func WithRetry(b Backend, policy RetryPolicy) Backend {
+	if policy.MaxAttempts <= 1 {
+		return b
+	}
+	return &retryBackend{Backend: b, policy: policy}
+}
+
+type retryBackend struct {
+	Backend
+	policy RetryPolicy
+}
+
+func (r *retryBackend) Generate(ctx context.Context, prompt string, opts GenerationOptions) ([]Image, error) {
+	for attempt := 1; ; attempt++ {
+		images, err := r.Backend.Generate(ctx, prompt, opts)
+		if err == nil || !IsRetryable(err) {
+			return images, err
+		}
+		if attempt >= r.policy.MaxAttempts {
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		delay := r.policy.backoff(attempt)
+		if after := retryAfter(err); after > 0 {
+			if after > r.policy.MaxDelay {
+				return nil, fmt.Errorf("server asked to wait %s, longer than %s: %w", after.Round(time.Second), r.policy.MaxDelay, err)
+			}
+			if after > delay {
+				delay = after
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyBackend fails with each of errs in turn, then succeeds
+type flakyBackend struct {
+	*StubClient
+	errs  []error
+	calls int
+}
+
+func (b *flakyBackend) Generate(ctx context.Context, prompt string, opts GenerationOptions) ([]Image, error) {
+	b.calls++
+	if b.calls <= len(b.errs) {
+		return nil, b.errs[b.calls-1]
+	}
+	return b.StubClient.Generate(ctx, prompt, opts)
+}
+
+func TestWithRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	rateLimited := classifyStatus(429, "RESOURCE_EXHAUSTED", "Resource has been exhausted", 0)
+	transient := classifyStatus(503, "UNAVAILABLE", "overloaded", 0)
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   string // "" for success
+	}{
+		{"rate limited then ok", []error{rateLimited}, 2, ""},
+		{"transient then ok", []error{transient, transient}, 3, ""},
+		{"gives up", []error{transient, transient, transient}, 3, "giving up after 3 attempts"},
+		{"quota not retried", []error{classifyStatus(429, "", "exceeded your current quota", 0)}, 1, "quota exhausted"},
+		{"auth not retried", []error{classifyStatus(403, "PERMISSION_DENIED", "", 0)}, 1, "authentication failed"},
+		{"invalid request not retried", []error{classifyStatus(400, "INVALID_ARGUMENT", "bad", 0)}, 1, "invalid request"},
+		{"safety block not retried", []error{&Error{Kind: KindSafetyBlocked}}, 1, "blocked by safety filter"},
+		{"unclassified not retried", []error{errors.New("no image data in response")}, 1, "no image data"},
+		{"retry-after beyond max delay", []error{classifyStatus(429, "", "slow down", time.Minute)}, 1, "longer than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &flakyBackend{StubClient: NewStubClient(), errs: tt.errs}
+			images, err := WithRetry(fake, policy).Generate(context.Background(), "prompt", GenerationOptions{})
+
+			if fake.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", fake.calls, tt.wantCalls)
+			}
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr == "" && len(images) == 0:
+				t.Error("no images after retrying")
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if err != nil && KindOf(err) != KindOf(tt.errs[len(tt.errs)-1]) {
+				t.Errorf("KindOf = %v, want the backend's %v", KindOf(err), KindOf(tt.errs[len(tt.errs)-1]))
+			}
+		})
+	}
+}
+
+func TestWithRetryHonoursRetryAfter(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+	fake := &flakyBackend{StubClient: NewStubClient(), errs: []error{classifyStatus(429, "", "slow down", 50*time.Millisecond)}}
+
+	start := time.Now()
+	if _, err := WithRetry(fake, policy).Generate(context.Background(), "prompt", GenerationOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("retried after %v, before the server's Retry-After", elapsed)
+	}
+}
+
+func TestWithRetryCancelledDuringBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	fake := &flakyBackend{StubClient: NewStubClient(), errs: []error{classifyStatus(503, "UNAVAILABLE", "", 0)}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := WithRetry(fake, policy).Generate(ctx, "prompt", GenerationOptions{})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+		if fake.calls != 1 {
+			t.Errorf("calls = %d, want 1", fake.calls)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Generate did not return after cancellation")
+	}
+}
+
+func TestWithRetryDisabled(t *testing.T) {
+	fake := &flakyBackend{StubClient: NewStubClient()}
+	if b := WithRetry(fake, RetryPolicy{MaxAttempts: 1}); b != Backend(fake) {
+		t.Error("WithRetry with one attempt wrapped the backend")
+	}
+}
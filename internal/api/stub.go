@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// StubModel is the model name reported by the stub backend
+const StubModel = "stub"
+
+// stubCapabilities accepts any aspect ratio
+var stubCapabilities = Capabilities{MaxImagesPerCall: 8, DefaultImagesPerCall: 1}
+
+func init() {
+	RegisterBackend("stub", StubModel, stubCapabilities, func(apiKey string) (Backend, error) {
+		return NewStubClient(), nil
+	})
+}
+
+// StubClient is an offline backend for testing without API access
+type StubClient struct {
+	model string
+}
+
+// NewStubClient creates a stub backend; it needs no API key
+func NewStubClient() *StubClient {
+	return &StubClient{model: StubModel}
+}
+
+// Name implements Backend
+func (c *StubClient) Name() string {
+	return "stub"
+}
+
+// Model implements Backend
+func (c *StubClient) Model() string {
+	return c.model
+}
+
+func (c *StubClient) setModel(model string) {
+	c.model = model
+}
+
+// Capabilities implements Backend
+func (c *StubClient) Capabilities() Capabilities {
+	return stubCapabilities
+}
+
+// Generate implements Backend, returning fixed placeholder images
+func (c *StubClient) Generate(ctx context.Context, prompt string, opts GenerationOptions) ([]Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	count := opts.CandidateCount
+	if count < 1 {
+		count = 1
+	}
+	images := make([]Image, count)
+	for i := range images {
+		images[i] = Image{Data: stubPNG(), MimeType: "image/png"}
+	}
+	return images, nil
+}
+
+// stubPNG returns a small valid PNG: a 64x64 gradient, so it passes the
+// same blank-image checks as real output
+func stubPNG() []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x * 4), G: uint8(y * 4), B: 160, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// GenerateText implements TextGenerator with a fixed reply
+func (c *StubClient) GenerateText(ctx context.Context, prompt string, jsonOutput bool) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if jsonOutput {
+		return `{"tagline": "Stub tool", "themes": ["abstract", "geometric", "minimal"]}`, nil
+	}
+	return "stub reply", nil
+}
+
+// Close implements Backend
+func (c *StubClient) Close() error {
+	return nil
+}
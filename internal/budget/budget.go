@@ -0,0 +1,156 @@
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rickhallett/beautifi/internal/api"
+)
+
+// ErrExceeded is returned when a request would go over a budget cap
+var ErrExceeded = errors.New("budget exceeded")
+
+// Estimate predicts the cost and wall time of generating a number of images
+type Estimate struct {
+	Images       int
+	PerImage     float64       // USD
+	PerImageTime time.Duration // average request latency
+	Samples      int           // historical durations the latency is based on, 0 = model default
+	Cost         float64       // USD
+	WallTime     time.Duration
+	Priced       bool // false when the model has no pricing entry
+}
+
+// NewEstimate estimates generating images requests with the given
+// pricing, latency, worker count and rate limit
+func NewEstimate(images int, price api.ModelPricing, priced bool, latency time.Duration, samples, parallel int, limit api.RateLimit) Estimate {
+	workers := parallel
+	if limit.MaxInFlight > 0 && limit.MaxInFlight < workers {
+		workers = limit.MaxInFlight
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	batches := (images + workers - 1) / workers
+	wall := time.Duration(batches) * latency
+	if limit.RequestsPerMinute > 0 {
+		// The limiter spaces request starts evenly
+		paced := time.Duration(images) * time.Minute / time.Duration(limit.RequestsPerMinute)
+		if paced > wall {
+			wall = paced
+		}
+	}
+
+	return Estimate{
+		Images:       images,
+		PerImage:     price.PerImage,
+		PerImageTime: latency,
+		Samples:      samples,
+		Cost:         float64(images) * price.PerImage,
+		WallTime:     wall,
+		Priced:       priced,
+	}
+}
+
+func (e Estimate) String() string {
+	cost := "unknown cost"
+	if e.Priced {
+		cost = fmt.Sprintf("$%.2f ($%.3f/image)", e.Cost, e.PerImage)
+	}
+	basis := "model default"
+	if e.Samples > 0 {
+		basis = fmt.Sprintf("avg of %d past images", e.Samples)
+	}
+	return fmt.Sprintf("%d images, %s, ~%s (%s/image, %s)",
+		e.Images, cost, e.WallTime.Round(time.Second), e.PerImageTime.Round(100*time.Millisecond), basis)
+}
+
+// Budget enforces caps on spend and image count across every project and
+// worker in a process. The zero value has no caps.
+type Budget struct {
+	MaxCost   float64 // USD, 0 = unlimited
+	MaxImages int     // 0 = unlimited
+
+	mu     sync.Mutex
+	cost   float64
+	images int
+}
+
+// Remaining returns the budget left before the caps are reached; a cap of
+// 0 reports as unlimited (-1)
+func (b *Budget) Remaining() (cost float64, images int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cost, images = -1, -1
+	if b.MaxCost > 0 {
+		cost = b.MaxCost - b.cost
+	}
+	if b.MaxImages > 0 {
+		images = b.MaxImages - b.images
+	}
+	return cost, images
+}
+
+// Check reports whether an estimated run fits in the remaining budget. A
+// cost cap cannot be checked against an unpriced estimate, so that fails.
+func (b *Budget) Check(e Estimate) error {
+	if b == nil {
+		return nil
+	}
+	cost, images := b.Remaining()
+	if cost >= 0 && !e.Priced && e.Images > 0 {
+		return fmt.Errorf("%w: the model has no pricing entry, so --max-cost cannot be enforced", ErrExceeded)
+	}
+	if images >= 0 && e.Images > images {
+		return fmt.Errorf("%w: %d images requested, %d left under --max-images %d", ErrExceeded, e.Images, images, b.MaxImages)
+	}
+	if cost >= 0 && e.Cost > cost+1e-9 {
+		return fmt.Errorf("%w: estimated $%.2f, $%.2f left under --max-cost $%.2f", ErrExceeded, e.Cost, cost, b.MaxCost)
+	}
+	return nil
+}
+
+// Reserve claims one image at price before a request is made. Call
+// Release if the request fails, since failed requests are not billed.
+func (b *Budget) Reserve(price float64) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.MaxImages > 0 && b.images+1 > b.MaxImages {
+		return fmt.Errorf("%w: --max-images %d reached", ErrExceeded, b.MaxImages)
+	}
+	if b.MaxCost > 0 && b.cost+price > b.MaxCost+1e-9 {
+		return fmt.Errorf("%w: --max-cost $%.2f reached ($%.2f spent)", ErrExceeded, b.MaxCost, b.cost)
+	}
+	b.images++
+	b.cost += price
+	return nil
+}
+
+// Release returns a reservation made by Reserve
+func (b *Budget) Release(price float64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.images--
+	b.cost -= price
+}
+
+// Spent returns the cost and image count reserved so far
+func (b *Budget) Spent() (cost float64, images int) {
+	if b == nil {
+		return 0, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.cost, b.images
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 
@@ -9,22 +10,67 @@ import (
 
 // Project represents a beautifi project configuration
 type Project struct {
-	Project  string   `yaml:"project"`
-	Tagline  string   `yaml:"tagline"`
-	Themes   []string `yaml:"themes"`
-	Styles   []string `yaml:"styles"`
+	// Extends names shared base files this project inherits from
+	Extends Extends `yaml:"extends,omitempty" json:"extends,omitempty"`
+
+	Project string   `yaml:"project" json:"project"`
+	Tagline string   `yaml:"tagline" json:"tagline"`
+	Themes  []string `yaml:"themes" json:"themes"`
+	Styles  []string `yaml:"styles" json:"styles"`
 
 	// Optional overrides
-	AspectRatio string            `yaml:"aspect_ratio,omitempty"` // e.g., "1:1", "16:9"
-	BasePrompt  string            `yaml:"base_prompt,omitempty"`  // Custom base prompt
-	Extras      map[string]string `yaml:"extras,omitempty"`       // Additional template vars
+	AspectRatio    string            `yaml:"aspect_ratio,omitempty" json:"aspect_ratio,omitempty"`       // e.g., "1:1", "16:9"
+	BasePrompt     string            `yaml:"base_prompt,omitempty" json:"base_prompt,omitempty"`         // Custom base prompt
+	Extras         map[string]string `yaml:"extras,omitempty" json:"extras,omitempty"`                   // Additional template vars
+	PromptTemplate string            `yaml:"prompt_template,omitempty" json:"prompt_template,omitempty"` // text/template for every prompt
+	StyleTemplates map[string]string `yaml:"style_templates,omitempty" json:"style_templates,omitempty"` // per-style prompt_template overrides
+	OutputFormat   string            `yaml:"output_format,omitempty" json:"output_format,omitempty"`     // png or jpeg; default keeps the returned type
+
+	// StylePresets defines or overrides styles for this project only
+	StylePresets map[string]StylePreset `yaml:"style_presets,omitempty" json:"style_presets,omitempty"`
+
+	Generation GenerationConfig `yaml:"generation,omitempty" json:"generation,omitempty"`
+}
+
+// GenerationConfig holds model parameters passed with every request.
+// Unset fields leave the choice to the model.
+type GenerationConfig struct {
+	ImageSize          string          `yaml:"image_size,omitempty" json:"image_size,omitempty"` // "1K", "2K", "4K"
+	Temperature        *float32        `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	Seed               *int32          `yaml:"seed,omitempty" json:"seed,omitempty"` // offset by variant number
+	CandidateCount     int             `yaml:"candidate_count,omitempty" json:"candidate_count,omitempty"`
+	ResponseModalities []string        `yaml:"response_modalities,omitempty" json:"response_modalities,omitempty"` // e.g., [TEXT, IMAGE]
+	SafetySettings     []SafetySetting `yaml:"safety_settings,omitempty" json:"safety_settings,omitempty"`
+
+	// Imagen predict parameters
+	NegativePrompt     string `yaml:"negative_prompt,omitempty" json:"negative_prompt,omitempty"`
+	PersonGeneration   string `yaml:"person_generation,omitempty" json:"person_generation,omitempty"`     // dont_allow, allow_adult, allow_all
+	SafetyFilterLevel  string `yaml:"safety_filter_level,omitempty" json:"safety_filter_level,omitempty"` // block_low_and_above, block_medium_and_above, block_only_high, block_none
+	AddWatermark       *bool  `yaml:"add_watermark,omitempty" json:"add_watermark,omitempty"`
+	EnhancePrompt      *bool  `yaml:"enhance_prompt,omitempty" json:"enhance_prompt,omitempty"`
+	Language           string `yaml:"language,omitempty" json:"language,omitempty"`
+	OutputMimeType     string `yaml:"output_mime_type,omitempty" json:"output_mime_type,omitempty"` // image/png, image/jpeg
+	CompressionQuality *int   `yaml:"compression_quality,omitempty" json:"compression_quality,omitempty"`
+	IncludeRaiReason   bool   `yaml:"include_rai_reason,omitempty" json:"include_rai_reason,omitempty"`
+}
+
+// SafetySetting sets the block threshold for one harm category
+type SafetySetting struct {
+	Category  string `yaml:"category" json:"category"`   // e.g., HARM_CATEGORY_HARASSMENT
+	Threshold string `yaml:"threshold" json:"threshold"` // e.g., BLOCK_ONLY_HIGH
 }
 
 // StylePreset defines a reusable style configuration
 type StylePreset struct {
-	Name        string   `yaml:"name"`
-	Description string   `yaml:"description"`
-	Keywords    []string `yaml:"keywords"`
+	Name             string   `yaml:"name,omitempty" json:"name,omitempty"`
+	Description      string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Keywords         []string `yaml:"keywords,omitempty" json:"keywords,omitempty"`
+	NegativeKeywords []string `yaml:"negative_keywords,omitempty" json:"negative_keywords,omitempty"` // sent as the negative prompt
+	PromptSuffix     string   `yaml:"prompt_suffix,omitempty" json:"prompt_suffix,omitempty"`         // appended to the prompt
+
+	// Source is where the preset was defined: "built-in", a styles file
+	// path, or "project"
+	Source string `yaml:"-" json:"source,omitempty"`
 }
 
 // DefaultStyles returns built-in style presets
@@ -73,41 +119,30 @@ func DefaultStyles() map[string]StylePreset {
 	}
 }
 
-// LoadProject loads a project configuration from YAML file
+// LoadProject loads a project configuration from YAML file, merged over
+// the files it extends. Decoding is strict: unknown keys are errors (see
+// ParseProject).
 func LoadProject(path string) (*Project, error) {
-	data, err := os.ReadFile(path)
+	doc, problems, err := ParseProject(path)
 	if err != nil {
-		return nil, fmt.Errorf("read file: %w", err)
-	}
-
-	var proj Project
-	if err := yaml.Unmarshal(data, &proj); err != nil {
-		return nil, fmt.Errorf("parse yaml: %w", err)
-	}
-
-	// Validate required fields
-	if proj.Project == "" {
-		return nil, fmt.Errorf("missing required field: project")
-	}
-	if len(proj.Themes) == 0 {
-		return nil, fmt.Errorf("missing required field: themes")
+		return nil, err
 	}
-	if len(proj.Styles) == 0 {
-		// Use defaults
-		proj.Styles = []string{"flat-minimal", "gradient-glass"}
+	if errs := Errors(problems); len(errs) > 0 {
+		return nil, problemsError(errs)
 	}
-
-	return &proj, nil
+	return doc.Project, nil
 }
 
 // SaveProject writes a project configuration to YAML file
 func SaveProject(path string, proj *Project) error {
-	data, err := yaml.Marshal(proj)
-	if err != nil {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(proj); err != nil {
 		return fmt.Errorf("marshal yaml: %w", err)
 	}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
 		return fmt.Errorf("write file: %w", err)
 	}
 
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Tags that change how a value merges with the one it inherits. Lists
+// replace inherited lists and maps merge key by key unless tagged.
+const (
+	appendTag  = "!append"  // append a list to the inherited list
+	replaceTag = "!replace" // replace an inherited map instead of merging
+)
+
+// Extends lists the files a project inherits from, written as one path or a
+// list of paths. Relative paths are resolved against the extending file's
+// directory; later files override earlier ones, and the project overrides
+// them all.
+type Extends []string
+
+// UnmarshalYAML accepts a single path as well as a list
+func (e *Extends) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*e = Extends{node.Value}
+		return nil
+	}
+	var paths []string
+	if err := node.Decode(&paths); err != nil {
+		return err
+	}
+	*e = paths
+	return nil
+}
+
+// projectLoader resolves extends chains into one merged document,
+// remembering which file every node came from
+type projectLoader struct {
+	files    map[*yaml.Node]string
+	stack    []string // absolute paths being loaded, for cycle detection
+	problems []Problem
+}
+
+// load parses path and the files it extends and returns the merged root
+// node, or nil if path is empty or not valid YAML
+func (l *projectLoader) load(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	var proj Project
+	root, problems := decodeStrict(path, data, &proj)
+	l.problems = append(l.problems, problems...)
+	if root == nil {
+		return nil, nil
+	}
+	l.register(root, path)
+	doc := &Document{Path: path, root: root}
+
+	abs, _ := filepath.Abs(path)
+	l.stack = append(l.stack, abs)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	var merged *yaml.Node
+	for i, parent := range proj.Extends {
+		key := fmt.Sprintf("extends.%d", i)
+		if !filepath.IsAbs(parent) {
+			parent = filepath.Join(filepath.Dir(path), parent)
+		}
+		parentAbs, _ := filepath.Abs(parent)
+
+		if j := slices.Index(l.stack, parentAbs); j >= 0 {
+			chain := make([]string, 0, len(l.stack)-j+1)
+			for _, p := range slices.Concat(l.stack[j:], []string{parentAbs}) {
+				chain = append(chain, filepath.Base(p))
+			}
+			l.problems = append(l.problems, doc.Problem(key, "extends cycle: %s", strings.Join(chain, " -> ")))
+			continue
+		}
+		if _, err := os.Stat(parent); err != nil {
+			l.problems = append(l.problems, doc.Problem(key, "extends %s: file not found", proj.Extends[i]))
+			continue
+		}
+
+		node, err := l.load(parent)
+		if err != nil {
+			return nil, err
+		}
+		if node != nil {
+			merged = l.merge(merged, node)
+		}
+	}
+	return l.merge(merged, l.withoutKey(root, "extends")), nil
+}
+
+// register records path as the file of node and everything below it, and
+// reports merge tags on values they do not apply to
+func (l *projectLoader) register(node *yaml.Node, path string) {
+	l.files[node] = path
+	switch {
+	case node.Tag == appendTag && node.Kind != yaml.SequenceNode:
+		l.problems = append(l.problems, Problem{File: path, Line: node.Line, Column: node.Column, Message: "!append only applies to lists"})
+	case node.Tag == replaceTag && node.Kind == yaml.ScalarNode:
+		l.problems = append(l.problems, Problem{File: path, Line: node.Line, Column: node.Column, Message: "!replace only applies to lists and maps"})
+	}
+	for _, c := range node.Content {
+		l.register(c, path)
+	}
+}
+
+// merge overlays over on base. Maps merge key by key, lists and scalars
+// replace, and the !append and !replace tags override that.
+func (l *projectLoader) merge(base, over *yaml.Node) *yaml.Node {
+	over = resolve(over)
+	if base == nil {
+		return l.untag(over)
+	}
+	base = resolve(base)
+
+	switch {
+	case over.Tag == replaceTag:
+		return l.untag(over)
+	case over.Tag == appendTag && over.Kind == yaml.SequenceNode && base.Kind == yaml.SequenceNode:
+		n := l.copyNode(over)
+		n.Content = append(slices.Clone(base.Content), over.Content...)
+		return n
+	case over.Kind == yaml.MappingNode && base.Kind == yaml.MappingNode:
+		n := l.copyNode(over)
+		n.Content = nil
+		merged := map[string]bool{}
+		for i := 0; i+1 < len(base.Content); i += 2 {
+			key, value := base.Content[i], base.Content[i+1]
+			if o := keyIndex(over, key.Value); o >= 0 {
+				key, value = over.Content[o], l.merge(value, over.Content[o+1])
+				merged[key.Value] = true
+			}
+			n.Content = append(n.Content, key, value)
+		}
+		for i := 0; i+1 < len(over.Content); i += 2 {
+			if !merged[over.Content[i].Value] {
+				n.Content = append(n.Content, over.Content[i], l.merge(nil, over.Content[i+1]))
+			}
+		}
+		return n
+	}
+	return l.untag(over)
+}
+
+// untag drops a merge tag so the node decodes as a plain list or map
+func (l *projectLoader) untag(node *yaml.Node) *yaml.Node {
+	if node.Tag != appendTag && node.Tag != replaceTag {
+		return node
+	}
+	return l.copyNode(node)
+}
+
+// copyNode returns a shallow copy of node without its merge tag, from the
+// same file
+func (l *projectLoader) copyNode(node *yaml.Node) *yaml.Node {
+	c := *node
+	if c.Tag == appendTag || c.Tag == replaceTag {
+		c.Tag = ""
+	}
+	l.files[&c] = l.files[node]
+	return &c
+}
+
+// withoutKey returns a mapping node without key
+func (l *projectLoader) withoutKey(node *yaml.Node, key string) *yaml.Node {
+	i := keyIndex(node, key)
+	if i < 0 {
+		return node
+	}
+	c := l.copyNode(node)
+	c.Content = slices.Concat(node.Content[:i], node.Content[i+2:])
+	return c
+}
+
+// keyIndex returns the index of key in a mapping node's content, or -1
+func keyIndex(node *yaml.Node, key string) int {
+	if node.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// ProjectFiles returns the project files in dir, sorted. Files that other
+// files in dir extend and that do not name a project are shared bases, not
+// projects, and are left out.
+func ProjectFiles(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	extended := map[string]bool{}
+	named := map[string]bool{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// Invalid files are reported when loaded, not here
+		var head struct {
+			Extends Extends `yaml:"extends"`
+			Project string  `yaml:"project"`
+		}
+		yaml.Unmarshal(data, &head)
+		named[filepath.Clean(path)] = head.Project != ""
+		for _, parent := range head.Extends {
+			if !filepath.IsAbs(parent) {
+				parent = filepath.Join(dir, parent)
+			}
+			extended[filepath.Clean(parent)] = true
+		}
+	}
+
+	var projects []string
+	for _, path := range paths {
+		if p := filepath.Clean(path); named[p] || !extended[p] {
+			projects = append(projects, path)
+		}
+	}
+	sort.Strings(projects)
+	return projects, nil
+}
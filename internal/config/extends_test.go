@@ -0,0 +1,205 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes name -> content into a new temporary directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const baseYAML = `tagline: Internal tool
+styles: [flat-minimal, gradient-glass]
+extras:
+  palette: navy and gold
+  mood: serious
+`
+
+func TestExtendsMerge(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		check func(*Project) any
+		want  any
+	}{
+		{
+			name: "scalars inherit",
+			files: map[string]string{
+				"project.yaml": "extends: base.yaml\nproject: bosun\nthemes: [ocean]\n",
+			},
+			check: func(p *Project) any { return p.Tagline },
+			want:  "Internal tool",
+		},
+		{
+			name: "maps merge key by key",
+			files: map[string]string{
+				"project.yaml": "extends: base.yaml\nproject: bosun\nthemes: [ocean]\nextras:\n  mood: calm\n",
+			},
+			check: func(p *Project) any { return p.Extras },
+			want:  map[string]string{"palette": "navy and gold", "mood": "calm"},
+		},
+		{
+			name: "lists replace",
+			files: map[string]string{
+				"project.yaml": "extends: base.yaml\nproject: bosun\nthemes: [ocean]\nstyles: [geometric]\n",
+			},
+			check: func(p *Project) any { return p.Styles },
+			want:  []string{"geometric"},
+		},
+		{
+			name: "append tag extends lists",
+			files: map[string]string{
+				"project.yaml": "extends: base.yaml\nproject: bosun\nthemes: [ocean]\nstyles: !append [geometric]\n",
+			},
+			check: func(p *Project) any { return p.Styles },
+			want:  []string{"flat-minimal", "gradient-glass", "geometric"},
+		},
+		{
+			name: "replace tag drops inherited keys",
+			files: map[string]string{
+				"project.yaml": "extends: base.yaml\nproject: bosun\nthemes: [ocean]\nextras: !replace\n  mood: calm\n",
+			},
+			check: func(p *Project) any { return p.Extras },
+			want:  map[string]string{"mood": "calm"},
+		},
+		{
+			name: "later bases win",
+			files: map[string]string{
+				"other.yaml":   "tagline: Other tool\n",
+				"project.yaml": "extends: [base.yaml, other.yaml]\nproject: bosun\nthemes: [ocean]\n",
+			},
+			check: func(p *Project) any { return p.Tagline },
+			want:  "Other tool",
+		},
+		{
+			name: "diamond is not a cycle",
+			files: map[string]string{
+				"left.yaml":    "extends: base.yaml\nthemes: [forest]\n",
+				"right.yaml":   "extends: base.yaml\nextras:\n  mood: calm\n",
+				"project.yaml": "extends: [left.yaml, right.yaml]\nproject: bosun\n",
+			},
+			check: func(p *Project) any { return []any{p.Themes, p.Extras} },
+			want:  []any{[]string{"forest"}, map[string]string{"palette": "navy and gold", "mood": "calm"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.files["base.yaml"] = baseYAML
+			dir := writeFiles(t, tt.files)
+
+			doc, problems, err := ParseProject(filepath.Join(dir, "project.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if errs := Errors(problems); len(errs) > 0 {
+				t.Fatalf("unexpected problems: %v", errs)
+			}
+			if got := tt.check(doc.Project); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtendsProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		wantFile string
+		wantLine int
+		wantMsg  string
+	}{
+		{
+			name: "cycle",
+			files: map[string]string{
+				"a.yaml":       "extends: b.yaml\ntagline: A\n",
+				"b.yaml":       "extends: a.yaml\n",
+				"project.yaml": "extends: a.yaml\nproject: bosun\nthemes: [ocean]\n",
+			},
+			wantFile: "b.yaml",
+			wantLine: 1,
+			wantMsg:  "extends cycle: a.yaml -> b.yaml -> a.yaml",
+		},
+		{
+			name: "missing base",
+			files: map[string]string{
+				"project.yaml": "project: bosun\nthemes: [ocean]\nextends: nope.yaml\n",
+			},
+			wantFile: "project.yaml",
+			wantLine: 3,
+			wantMsg:  "extends nope.yaml: file not found",
+		},
+		{
+			name: "problem in a base file",
+			files: map[string]string{
+				"base.yaml":    "tagline: Internal tool\n\naspect_ratio: wide\n",
+				"project.yaml": "extends: base.yaml\nproject: bosun\nthemes: [ocean]\n",
+			},
+			wantFile: "base.yaml",
+			wantLine: 3,
+			wantMsg:  `aspect_ratio "wide" is not of the form W:H`,
+		},
+		{
+			name: "unknown key in a base file",
+			files: map[string]string{
+				"base.yaml":    "tagline: Internal tool\naspect-ratio: 1:1\n",
+				"project.yaml": "extends: base.yaml\nproject: bosun\nthemes: [ocean]\n",
+			},
+			wantFile: "base.yaml",
+			wantLine: 2,
+			wantMsg:  `unknown field "aspect-ratio"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, tt.files)
+
+			_, problems, err := ParseProject(filepath.Join(dir, "project.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range Errors(problems) {
+				if filepath.Base(p.File) == tt.wantFile && p.Line == tt.wantLine && strings.Contains(p.Message, tt.wantMsg) {
+					return
+				}
+			}
+			t.Errorf("no problem %q at %s:%d; got %v", tt.wantMsg, tt.wantFile, tt.wantLine, problems)
+		})
+	}
+}
+
+func TestProjectFiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"org.yaml":    baseYAML,
+		"bosun.yaml":  "extends: org.yaml\nproject: bosun\nthemes: [ocean]\n",
+		"wasp.yaml":   "project: wasp\nthemes: [insects]\n",
+		"shared.yaml": "extends: bosun.yaml\nproject: shared\nthemes: [x]\n",
+		"loose.yaml":  "tagline: not extended by anything\n",
+	})
+
+	paths, err := ProjectFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range paths {
+		got = append(got, filepath.Base(p))
+	}
+	// org.yaml is only a base; bosun.yaml is extended but names a project
+	want := []string{"bosun.yaml", "loose.yaml", "shared.yaml", "wasp.yaml"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ProjectFiles = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Settings are user-wide defaults read from config.yaml in the config
+// directory. Unset fields leave the built-in defaults in place.
+type Settings struct {
+	Backend     string   `yaml:"backend,omitempty" json:"backend,omitempty"`
+	Model       string   `yaml:"model,omitempty" json:"model,omitempty"`
+	OutputDir   string   `yaml:"output_dir,omitempty" json:"output_dir,omitempty"`
+	Variants    int      `yaml:"variants,omitempty" json:"variants,omitempty"`
+	Parallel    int      `yaml:"parallel,omitempty" json:"parallel,omitempty"`
+	Retries     *int     `yaml:"retries,omitempty" json:"retries,omitempty"`
+	RPM         int      `yaml:"rpm,omitempty" json:"rpm,omitempty"`
+	MaxInFlight int      `yaml:"max_inflight,omitempty" json:"max_inflight,omitempty"`
+	Styles      []string `yaml:"styles,omitempty" json:"styles,omitempty"`
+
+	// Where API keys come from when not in the environment or a .env
+	// file; Credentials overrides these per backend
+	APIKeyFile    string                `yaml:"api_key_file,omitempty" json:"api_key_file,omitempty"`
+	APIKeyCommand string                `yaml:"api_key_command,omitempty" json:"api_key_command,omitempty"`
+	Credentials   map[string]Credential `yaml:"credentials,omitempty" json:"credentials,omitempty"`
+}
+
+// Credential configures the API key source for one backend
+type Credential struct {
+	APIKeyFile    string `yaml:"api_key_file,omitempty" json:"api_key_file,omitempty"`       // must be mode 0600
+	APIKeyCommand string `yaml:"api_key_command,omitempty" json:"api_key_command,omitempty"` // prints the key, e.g. "pass show gemini"
+}
+
+// SettingsPath returns the path of the user config file
+func SettingsPath(cfgDir string) string {
+	return filepath.Join(cfgDir, "config.yaml")
+}
+
+// ParseSettings strictly decodes the user config file, checking backend
+// against the known backends. A missing file yields empty settings; err is
+// only set when the file cannot be read.
+func ParseSettings(cfgDir string, backends []string) (*Settings, []Problem, error) {
+	path := SettingsPath(cfgDir)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Settings{}, nil, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("read settings: %w", err)
+	}
+
+	var s Settings
+	root, problems := decodeStrict(path, data, &s)
+	if s.Backend != "" && !slices.Contains(backends, s.Backend) {
+		msg := fmt.Sprintf("unknown backend %q", s.Backend)
+		if suggestion := Suggest(s.Backend, backends); suggestion != "" {
+			msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+		}
+		doc := &Document{Path: path, root: root}
+		problems = append(problems, doc.Problem("backend", "%s (available: %s)", msg, strings.Join(backends, ", ")))
+	}
+	SortProblems(problems)
+	return &s, problems, nil
+}
+
+// LoadSettings loads the user config file, failing on any problem
+func LoadSettings(cfgDir string, backends []string) (*Settings, error) {
+	s, problems, err := ParseSettings(cfgDir, backends)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, problemsError(problems)
+	}
+	return s, nil
+}
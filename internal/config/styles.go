@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StylesDir returns the directory user style presets are loaded from
+func StylesDir(cfgDir string) string {
+	return filepath.Join(cfgDir, "styles")
+}
+
+// LoadStyles returns the style presets available to proj: the built-ins,
+// overridden by each file in cfgDir/styles (in name order), overridden by
+// the project's style_presets. A later definition replaces a style
+// entirely. proj may be nil.
+//
+// Each styles file maps style names to presets:
+//
+//	house-mono:
+//	  description: Monochrome house style
+//	  keywords: [monochrome, bold outline]
+//	  negative_keywords: [gradient]
+//	  prompt_suffix: in the Acme brand palette
+func LoadStyles(cfgDir string, proj *Project) (map[string]StylePreset, error) {
+	presets := DefaultStyles()
+	for name, preset := range presets {
+		preset.Source = "built-in"
+		presets[name] = preset
+	}
+
+	files, err := filepath.Glob(filepath.Join(StylesDir(cfgDir), "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read styles: %w", err)
+		}
+		var defined map[string]StylePreset
+		if err := yaml.Unmarshal(data, &defined); err != nil {
+			return nil, fmt.Errorf("parse styles %s: %w", path, err)
+		}
+		mergeStyles(presets, defined, path)
+	}
+
+	if proj != nil {
+		mergeStyles(presets, proj.StylePresets, "project")
+	}
+	return presets, nil
+}
+
+func mergeStyles(presets, defined map[string]StylePreset, source string) {
+	for name, preset := range defined {
+		preset.Name = name
+		preset.Source = source
+		presets[name] = preset
+	}
+}
+
+// Suggest returns the entry of known closest to name, or "" if none is
+// close enough to be a likely typo
+func Suggest(name string, known []string) string {
+	best, bestDist := "", len(name)/3+2
+	for _, k := range known {
+		if d := levenshtein(strings.ToLower(name), strings.ToLower(k)); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// StyleNames returns the names of presets, sorted
+func StyleNames(presets map[string]StylePreset) []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,402 @@
+package config
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectSchema is the JSON Schema for project files
+//
+//go:embed project.schema.json
+var ProjectSchema []byte
+
+// Problem is a config error or warning at a position in a file
+type Problem struct {
+	File    string
+	Line    int // 1-based; 0 if unknown
+	Column  int // 1-based; 0 if unknown
+	Message string
+	Warning bool
+}
+
+func (p Problem) String() string {
+	pos := p.File
+	if p.Line > 0 {
+		pos += ":" + strconv.Itoa(p.Line)
+		if p.Column > 0 {
+			pos += ":" + strconv.Itoa(p.Column)
+		}
+	}
+	if p.Warning {
+		return pos + ": warning: " + p.Message
+	}
+	return pos + ": " + p.Message
+}
+
+// Errors returns the problems that are not warnings
+func Errors(problems []Problem) []Problem {
+	var errs []Problem
+	for _, p := range problems {
+		if !p.Warning {
+			errs = append(errs, p)
+		}
+	}
+	return errs
+}
+
+// SortProblems orders problems by file and position
+func SortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+}
+
+// Document is a parsed project file that keeps the position of every key,
+// so later checks can report where a value came from
+type Document struct {
+	Path    string
+	Project *Project
+	root    *yaml.Node
+	files   map[*yaml.Node]string // file of each node, for inherited values
+}
+
+// ParseProject strictly decodes a project file and the files it extends.
+// Syntax errors, unknown keys, type mismatches, extends cycles and missing
+// required fields are returned as problems; err is only set when a file
+// cannot be read. Project is nil if any problem is an error.
+func ParseProject(path string) (*Document, []Problem, error) {
+	l := &projectLoader{files: map[*yaml.Node]string{}}
+	root, err := l.load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	problems := l.problems
+	doc := &Document{Path: path, root: root, files: l.files}
+	if root == nil {
+		if len(problems) == 0 {
+			problems = append(problems, Problem{File: path, Line: 1, Column: 1, Message: "empty project file"})
+		}
+		return doc, problems, nil
+	}
+
+	// Required fields can only be checked once the whole chain decodes
+	var proj Project
+	if len(Errors(problems)) == 0 {
+		if err := root.Decode(&proj); err != nil {
+			problems = append(problems, yamlProblems(path, err, nil)...)
+		}
+	}
+	if len(Errors(problems)) > 0 {
+		SortProblems(problems)
+		return doc, problems, nil
+	}
+
+	if proj.Project == "" {
+		problems = append(problems, doc.Problem("project", "missing required field: project"))
+	}
+	if len(proj.Themes) == 0 {
+		problems = append(problems, doc.Problem("themes", "missing required field: themes"))
+	}
+	if len(proj.Styles) == 0 {
+		p := doc.Problem("styles", "styles not set; using flat-minimal, gradient-glass")
+		p.Warning = true
+		problems = append(problems, p)
+		proj.Styles = []string{"flat-minimal", "gradient-glass"}
+	}
+	problems = append(problems, doc.checkValues(&proj)...)
+
+	if len(Errors(problems)) == 0 {
+		doc.Project = &proj
+	}
+	SortProblems(problems)
+	return doc, problems, nil
+}
+
+// aspectRatioPattern matches ratios such as 1:1 and 16:9
+var aspectRatioPattern = regexp.MustCompile(`^[0-9]+:[0-9]+$`)
+
+// CheckAspectRatio returns an error unless ratio is empty or of the form W:H
+func CheckAspectRatio(ratio string) error {
+	if ratio != "" && !aspectRatioPattern.MatchString(ratio) {
+		return fmt.Errorf("aspect_ratio %q is not of the form W:H, e.g. 16:9", ratio)
+	}
+	return nil
+}
+
+// checkValues applies the value constraints of project.schema.json, so
+// config validate and editors agree on what is valid
+func (d *Document) checkValues(proj *Project) []Problem {
+	var problems []Problem
+	enum := func(key, value string, allowed ...string) {
+		if value != "" && !slices.Contains(allowed, value) {
+			problems = append(problems, d.Problem(key, "%s %q is not one of %s", lastKey(key), value, strings.Join(allowed, ", ")))
+		}
+	}
+	between := func(key string, value, min, max float64) {
+		if value < min || value > max {
+			problems = append(problems, d.Problem(key, "%s %v is out of range %v-%v", lastKey(key), value, min, max))
+		}
+	}
+
+	for i, t := range proj.Themes {
+		if strings.TrimSpace(t) == "" {
+			problems = append(problems, d.Problem(fmt.Sprintf("themes.%d", i), "empty theme"))
+		}
+	}
+	for i, s := range proj.Styles {
+		if strings.TrimSpace(s) == "" {
+			problems = append(problems, d.Problem(fmt.Sprintf("styles.%d", i), "empty style"))
+		}
+	}
+	if err := CheckAspectRatio(proj.AspectRatio); err != nil {
+		problems = append(problems, d.Problem("aspect_ratio", "%v", err))
+	}
+
+	g := proj.Generation
+	enum("generation.image_size", g.ImageSize, "1K", "2K", "4K")
+	if g.Temperature != nil {
+		between("generation.temperature", float64(*g.Temperature), 0, 2)
+	}
+	if g.CandidateCount != 0 {
+		between("generation.candidate_count", float64(g.CandidateCount), 1, 8)
+	}
+	for i, m := range g.ResponseModalities {
+		enum(fmt.Sprintf("generation.response_modalities.%d", i), m, "TEXT", "IMAGE")
+	}
+	for i, s := range g.SafetySettings {
+		if !strings.HasPrefix(s.Category, "HARM_CATEGORY_") {
+			problems = append(problems, d.Problem(fmt.Sprintf("generation.safety_settings.%d.category", i), "category %q does not start with HARM_CATEGORY_", s.Category))
+		}
+	}
+	enum("generation.person_generation", g.PersonGeneration, "dont_allow", "allow_adult", "allow_all")
+	enum("generation.safety_filter_level", g.SafetyFilterLevel, "block_low_and_above", "block_medium_and_above", "block_only_high", "block_none")
+	enum("generation.output_mime_type", g.OutputMimeType, "image/png", "image/jpeg")
+	if g.CompressionQuality != nil {
+		between("generation.compression_quality", float64(*g.CompressionQuality), 0, 100)
+	}
+	return problems
+}
+
+// lastKey returns the last named key of a dotted path, skipping indices
+func lastKey(keyPath string) string {
+	parts := strings.Split(keyPath, ".")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if _, err := strconv.Atoi(parts[i]); err != nil {
+			return parts[i]
+		}
+	}
+	return keyPath
+}
+
+// decodeStrict decodes YAML data into v, reporting syntax errors, keys
+// with no matching field and type mismatches as problems. The returned
+// root node is nil if data is empty or not valid YAML.
+func decodeStrict(path string, data []byte, v any) (*yaml.Node, []Problem) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, yamlProblems(path, err, nil)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+
+	var problems []Problem
+	columns := map[int]int{}
+	checkKeys(path, root, reflect.TypeOf(v).Elem(), "", columns, &problems)
+
+	// Decode strictly for type errors. Unknown keys were already reported
+	// by checkKeys, with columns and suggestions.
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil {
+		for _, p := range yamlProblems(path, err, columns) {
+			if !strings.Contains(p.Message, "not found in type") {
+				problems = append(problems, p)
+			}
+		}
+	}
+	return root, problems
+}
+
+// problemsError joins problems into one error, one per line
+func problemsError(problems []Problem) error {
+	msgs := make([]string, len(problems))
+	for i, p := range problems {
+		msgs[i] = p.String()
+	}
+	return errors.New(strings.Join(msgs, "\n"))
+}
+
+// Problem returns an error positioned at the value of keyPath, a dotted
+// path such as "generation.image_size" or "styles.2". Missing keys are
+// reported at their closest present parent.
+func (d *Document) Problem(keyPath, format string, args ...any) Problem {
+	p := Problem{File: d.Path, Message: fmt.Sprintf(format, args...)}
+	if node := d.lookup(keyPath); node != nil {
+		p.Line, p.Column = node.Line, node.Column
+		if file, ok := d.files[node]; ok {
+			p.File = file
+		}
+	}
+	return p
+}
+
+// lookup finds the node for keyPath, or its closest present parent
+func (d *Document) lookup(keyPath string) *yaml.Node {
+	node := d.root
+	if node == nil || keyPath == "" {
+		return node
+	}
+	for _, part := range strings.Split(keyPath, ".") {
+		next := child(node, part)
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+	return node
+}
+
+// child returns the value for key in a mapping, or the element at index
+// key in a sequence
+func child(node *yaml.Node, key string) *yaml.Node {
+	node = resolve(node)
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(node.Content) {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+func resolve(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		return node.Alias
+	}
+	return node
+}
+
+// checkKeys reports mapping keys that do not match a yaml field of t,
+// recursing into nested structs, maps and slices. columns records the
+// column of the first scalar value on each line, to position decode errors.
+func checkKeys(file string, node *yaml.Node, t reflect.Type, path string, columns map[int]int, problems *[]Problem) {
+	node = resolve(node)
+	if _, ok := columns[node.Line]; !ok && node.Kind == yaml.ScalarNode {
+		columns[node.Line] = node.Column
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				msg := fmt.Sprintf("unknown field %q", key.Value)
+				if path != "" {
+					msg += " in " + path
+				}
+				if s := Suggest(key.Value, sortedKeys(fields)); s != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", s)
+				}
+				*problems = append(*problems, Problem{File: file, Line: key.Line, Column: key.Column, Message: msg})
+				continue
+			}
+			checkKeys(file, value, field, joinPath(path, key.Value), columns, problems)
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkKeys(file, node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value), columns, problems)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			checkKeys(file, item, t.Elem(), joinPath(path, strconv.Itoa(i)), columns, problems)
+		}
+	}
+}
+
+// yamlFields maps the yaml keys of struct t to their field types
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func sortedKeys(m map[string]reflect.Type) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// yamlLine matches the "line N: message" form of yaml.v3 errors
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// yamlProblems converts a yaml.v3 error into positioned problems
+func yamlProblems(file string, err error, columns map[int]int) []Problem {
+	var messages []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+
+	problems := make([]Problem, 0, len(messages))
+	for _, msg := range messages {
+		p := Problem{File: file, Message: strings.TrimPrefix(strings.TrimSpace(msg), "yaml: ")}
+		if m := yamlLine.FindStringSubmatch(p.Message); m != nil {
+			p.Line, _ = strconv.Atoi(m[1])
+			p.Column = columns[p.Line]
+			p.Message = m[2]
+		}
+		problems = append(problems, p)
+	}
+	return problems
+}
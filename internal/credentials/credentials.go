@@ -0,0 +1,199 @@
+// Package credentials finds API keys in the environment, .env files, key
+// files and secret helper commands.
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no source has a key
+var ErrNotFound = errors.New("no API key found")
+
+// commandTimeout bounds api_key_command, which may wait for a password
+// manager to unlock
+const commandTimeout = 2 * time.Minute
+
+// Key is an API key and where it came from. It formats as its source only,
+// so it can be printed or logged without revealing the key.
+type Key struct {
+	value  string
+	Source string
+}
+
+// Value returns the key itself. Pass it to the backend; never print it.
+func (k Key) Value() string {
+	return k.value
+}
+
+func (k Key) String() string {
+	return "API key from " + k.Source
+}
+
+// GoString keeps %#v from printing the key
+func (k Key) GoString() string {
+	return k.String()
+}
+
+// Sources configures where keys are looked up
+type Sources struct {
+	// Names are the key names a backend accepts, most specific first
+	// (see api.Capabilities.APIKeys)
+	Names []string
+	// EnvDirs are searched in order for a .env file
+	EnvDirs []string
+	// KeyFile is a file holding only the key. It must not be readable by
+	// group or others.
+	KeyFile string
+	// KeyDir holds optional <name>.key files, used when KeyFile is unset
+	KeyDir string
+	// Command prints the key on stdout, e.g. "pass show gemini"
+	Command string
+}
+
+// EnvVar returns the environment variable for a key name, e.g. GEMINI_API_KEY
+func EnvVar(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_API_KEY"
+}
+
+// Resolve finds a key, checking in order: environment variables, .env
+// files, the key file and the key command. Each step tries every name
+// before moving on. Errors never include key material.
+func Resolve(ctx context.Context, s Sources) (Key, error) {
+	for _, name := range s.Names {
+		if v := strings.TrimSpace(os.Getenv(EnvVar(name))); v != "" {
+			return Key{value: v, Source: "env " + EnvVar(name)}, nil
+		}
+	}
+
+	for _, dir := range s.EnvDirs {
+		path := filepath.Join(dir, ".env")
+		vars, err := readDotEnv(path)
+		if err != nil {
+			return Key{}, err
+		}
+		for _, name := range s.Names {
+			if v := vars[EnvVar(name)]; v != "" {
+				return Key{value: v, Source: path}, nil
+			}
+		}
+	}
+
+	if s.KeyFile != "" {
+		return readKeyFile(s.KeyFile)
+	}
+	if s.KeyDir != "" {
+		for _, name := range s.Names {
+			path := filepath.Join(s.KeyDir, name+".key")
+			if _, err := os.Stat(path); err == nil {
+				return readKeyFile(path)
+			}
+		}
+	}
+
+	if s.Command != "" {
+		return runCommand(ctx, s.Command)
+	}
+	return Key{}, ErrNotFound
+}
+
+// readDotEnv parses KEY=value lines, with optional export prefixes,
+// quotes and # comments. A missing file yields no variables.
+func readDotEnv(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		vars[strings.TrimSpace(key)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// readKeyFile reads a key file, refusing files other users can read
+func readKeyFile(path string) (Key, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return Key{}, fmt.Errorf("key file: %w", err)
+	}
+	// Windows has no Unix permission bits to check
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0o077 != 0 {
+		return Key{}, fmt.Errorf("key file %s is accessible by other users (mode %04o); run: chmod 600 %s",
+			path, fi.Mode().Perm(), path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, fmt.Errorf("key file: %w", err)
+	}
+	v := strings.TrimSpace(string(data))
+	if v == "" {
+		return Key{}, fmt.Errorf("key file %s is empty", path)
+	}
+	return Key{value: v, Source: path}, nil
+}
+
+// runCommand runs a secret helper through the shell and uses the first line
+// of its output. Its stderr and stdin stay attached to the terminal so it
+// can prompt to unlock.
+func runCommand(ctx context.Context, command string) (Key, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	// The output is the key, so it is never part of an error
+	if err := cmd.Run(); err != nil {
+		return Key{}, fmt.Errorf("api_key_command %q failed: %w", command, err)
+	}
+	v, _, _ := strings.Cut(strings.TrimSpace(out.String()), "\n")
+	if v = strings.TrimSpace(v); v == "" {
+		return Key{}, fmt.Errorf("api_key_command %q printed nothing", command)
+	}
+	return Key{value: v, Source: "api_key_command"}, nil
+}
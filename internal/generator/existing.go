@@ -0,0 +1,105 @@
+package generator
+
+import (
+	"encoding/json"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Metadata is the JSON sidecar written next to each image
+type Metadata struct {
+	Prompt   string `json:"prompt"`
+	Theme    string `json:"theme"`
+	Style    string `json:"style"`
+	Variant  int    `json:"variant"`
+	Model    string `json:"model,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	Text     string `json:"text,omitempty"` // text returned with the image
+	Hash     string `json:"hash,omitempty"` // see PromptSpec.Hash
+}
+
+func metadataPath(imagePath string) string {
+	return strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + ".json"
+}
+
+// readMetadata loads the sidecar for an image, if present
+func readMetadata(imagePath string) (*Metadata, error) {
+	data, err := os.ReadFile(metadataPath(imagePath))
+	if err != nil {
+		return nil, err
+	}
+	var meta Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// validImage reports whether path holds a valid, non-blank image whose
+// extension matches its content
+func validImage(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	decoded, err := decodeImage(data)
+	return err == nil && extension(decoded.mimeType) == strings.ToLower(filepath.Ext(path))
+}
+
+// IsGenerated reports whether outDir already holds a valid image for spec
+// whose metadata matches the spec's prompt and position in the matrix.
+func IsGenerated(outDir string, spec PromptSpec) bool {
+	outPath, ok := findImage(outDir, spec)
+	if !ok || !validImage(outPath) {
+		return false
+	}
+	meta, err := readMetadata(outPath)
+	if err != nil {
+		return false
+	}
+	return meta.Prompt == spec.Prompt &&
+		meta.Theme == spec.Theme &&
+		meta.Style == spec.Style &&
+		meta.Variant == spec.Variant
+}
+
+// IsCurrent reports whether outDir holds a valid image for spec whose
+// recorded hash matches the spec's hash for model, i.e. the output is not
+// stale. Images without a recorded hash are always stale.
+func IsCurrent(outDir string, spec PromptSpec, model string) bool {
+	outPath, ok := findImage(outDir, spec)
+	if !ok || !validImage(outPath) {
+		return false
+	}
+	meta, err := readMetadata(outPath)
+	if err != nil {
+		return false
+	}
+	return meta.Hash != "" && meta.Hash == spec.Hash(model)
+}
+
+// CountCurrent returns how many prompts have up-to-date outputs in outDir
+func CountCurrent(outDir string, prompts []PromptSpec, model string) int {
+	count := 0
+	for _, p := range prompts {
+		if IsCurrent(outDir, p, model) {
+			count++
+		}
+	}
+	return count
+}
+
+// CountGenerated returns how many prompts already have outputs in outDir
+func CountGenerated(outDir string, prompts []PromptSpec) int {
+	count := 0
+	for _, p := range prompts {
+		if IsGenerated(outDir, p) {
+			count++
+		}
+	}
+	return count
+}
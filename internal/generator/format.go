@@ -0,0 +1,186 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// formats maps supported image MIME types to file extensions. The first
+// entries are also the targets output_format can transcode to.
+var formats = []struct {
+	mimeType string
+	ext      string
+}{
+	{"image/png", ".png"},
+	{"image/jpeg", ".jpg"},
+	{"image/webp", ".webp"},
+	{"image/gif", ".gif"},
+}
+
+// formatAliases maps output_format values to MIME types
+var formatAliases = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"jpg":  "image/jpeg",
+}
+
+// FormatMimeType returns the MIME type for an output_format value. An empty
+// format keeps whatever type the backend returned.
+func FormatMimeType(format string) (string, error) {
+	if format == "" {
+		return "", nil
+	}
+	mimeType, ok := formatAliases[strings.ToLower(format)]
+	if !ok {
+		return "", fmt.Errorf("unsupported output_format %q (use png or jpeg)", format)
+	}
+	return mimeType, nil
+}
+
+// extension returns the file extension for a MIME type, or "" if unknown
+func extension(mimeType string) string {
+	for _, f := range formats {
+		if f.mimeType == mimeType {
+			return f.ext
+		}
+	}
+	return ""
+}
+
+// sniffMimeType identifies image data by its content. The MIME type the API
+// reports is not trusted: it has been seen to disagree with the bytes.
+func sniffMimeType(data []byte) string {
+	mimeType := http.DetectContentType(data)
+	if extension(mimeType) == "" {
+		return ""
+	}
+	return mimeType
+}
+
+// decodedImage is a validated image payload
+type decodedImage struct {
+	mimeType string
+	img      image.Image // nil for formats the standard library cannot decode
+}
+
+// decodeImage checks that data is a real, non-blank image. WebP can only
+// be checked for its dimensions.
+func decodeImage(data []byte) (*decodedImage, error) {
+	mimeType := sniffMimeType(data)
+	if mimeType == "" {
+		return nil, fmt.Errorf("not an image (detected %s)", http.DetectContentType(data))
+	}
+
+	if mimeType == "image/webp" {
+		width, height, err := webpSize(data)
+		if err != nil {
+			return nil, err
+		}
+		if width == 0 || height == 0 {
+			return nil, fmt.Errorf("image has zero size (%dx%d)", width, height)
+		}
+		return &decodedImage{mimeType: mimeType}, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode %s: %w", mimeType, err)
+	}
+	if b := img.Bounds(); b.Dx() == 0 || b.Dy() == 0 {
+		return nil, fmt.Errorf("image has zero size (%dx%d)", b.Dx(), b.Dy())
+	}
+	if isUniform(img) {
+		return nil, fmt.Errorf("image is blank (every pixel is the same colour)")
+	}
+	return &decodedImage{mimeType: mimeType, img: img}, nil
+}
+
+// isUniform reports whether every pixel of img has the same colour
+func isUniform(img image.Image) bool {
+	b := img.Bounds()
+	r0, g0, b0, a0 := img.At(b.Min.X, b.Min.Y).RGBA()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := img.At(x, y).RGBA()
+			if r != r0 || g != g0 || bl != b0 || a != a0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// webpSize reads the canvas size from a WebP header (VP8, VP8L or VP8X)
+func webpSize(data []byte) (int, int, error) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, fmt.Errorf("truncated webp header")
+	}
+	chunk := data[12:]
+	switch string(chunk[0:4]) {
+	case "VP8 ":
+		// Frame tag (3 bytes) and start code (3 bytes) precede the size
+		w := binary.LittleEndian.Uint16(chunk[14:16]) & 0x3fff
+		h := binary.LittleEndian.Uint16(chunk[16:18]) & 0x3fff
+		return int(w), int(h), nil
+	case "VP8L":
+		bits := binary.LittleEndian.Uint32(chunk[9:13])
+		return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1, nil
+	case "VP8X":
+		w := uint32(chunk[12]) | uint32(chunk[13])<<8 | uint32(chunk[14])<<16
+		h := uint32(chunk[15]) | uint32(chunk[16])<<8 | uint32(chunk[17])<<16
+		return int(w) + 1, int(h) + 1, nil
+	default:
+		return 0, 0, fmt.Errorf("unknown webp chunk %q", chunk[0:4])
+	}
+}
+
+// encodeImage transcodes a decoded image to mimeType
+func encodeImage(d *decodedImage, mimeType string, quality *int) ([]byte, error) {
+	if d.img == nil {
+		return nil, fmt.Errorf("cannot transcode %s to %s", d.mimeType, mimeType)
+	}
+
+	var buf bytes.Buffer
+	switch mimeType {
+	case "image/png":
+		if err := png.Encode(&buf, d.img); err != nil {
+			return nil, err
+		}
+	case "image/jpeg":
+		q := jpeg.DefaultQuality
+		if quality != nil {
+			q = *quality
+		}
+		if err := jpeg.Encode(&buf, d.img, &jpeg.Options{Quality: q}); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("cannot encode %s", mimeType)
+	}
+	return buf.Bytes(), nil
+}
+
+// findImage returns the path of the image saved for spec, if any. When the
+// spec has no fixed format the image may have any supported extension.
+func findImage(outDir string, spec PromptSpec) (string, bool) {
+	planned := filepath.Join(outDir, spec.Filename)
+	if _, err := os.Stat(planned); err == nil || spec.Format != "" {
+		return planned, err == nil
+	}
+
+	stem := strings.TrimSuffix(planned, filepath.Ext(planned))
+	for _, f := range formats {
+		if _, err := os.Stat(stem + f.ext); err == nil {
+			return stem + f.ext, true
+		}
+	}
+	return "", false
+}
@@ -2,14 +2,20 @@ package generator
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/rickhallett/beautifi/internal/api"
+	"github.com/rickhallett/beautifi/internal/budget"
 	"github.com/rickhallett/beautifi/internal/config"
+	"github.com/rickhallett/beautifi/internal/pool"
 )
 
 // PromptSpec represents a single generation task
@@ -18,7 +24,25 @@ type PromptSpec struct {
 	Style    string `json:"style"`
 	Variant  int    `json:"variant"`
 	Prompt   string `json:"prompt"`
-	Filename string `json:"filename"`
+	Filename string `json:"filename"`         // planned name; the extension follows the saved type
+	Format   string `json:"format,omitempty"` // MIME type to save as, "" keeps the returned type
+
+	Options api.GenerationOptions `json:"options"`
+}
+
+// Hash returns a content hash of everything that determines the image
+// for this spec: the prompt, the model, the generation parameters and the
+// output format. Theme, style and variant only matter through the prompt
+// they produce.
+func (p PromptSpec) Hash(model string) string {
+	params, _ := json.Marshal(p.Options)
+	h := sha256.New()
+	fmt.Fprintf(h, "prompt=%s\nmodel=%s\nparams=%s\n", p.Prompt, model, params)
+	// Only hashed when set, so hashes from before output_format still match
+	if p.Format != "" {
+		fmt.Fprintf(h, "format=%s\n", p.Format)
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // GenerationResult captures the outcome of a single generation
@@ -26,19 +50,39 @@ type GenerationResult struct {
 	Spec     PromptSpec `json:"spec"`
 	Success  bool       `json:"success"`
 	Error    string     `json:"error,omitempty"`
+	Kind     string     `json:"kind,omitempty"` // error classification, see api.ErrorKind
 	FilePath string     `json:"file_path,omitempty"`
+	Skipped  bool       `json:"skipped,omitempty"` // already generated, not requested again
+
+	Duration time.Duration `json:"duration_ns,omitempty"` // time spent generating and saving
 }
 
-// GeneratePrompts creates all prompt combinations for a project
-func GeneratePrompts(proj *config.Project, styles []string, variants int) []PromptSpec {
+// GeneratePrompts creates all prompt combinations for a project, rendering
+// each through the project's prompt templates
+func GeneratePrompts(proj *config.Project, presets map[string]config.StylePreset, styles []string, variants int) ([]PromptSpec, error) {
 	var prompts []PromptSpec
-	stylePresets := config.DefaultStyles()
+
+	templates, err := parsePromptTemplates(proj)
+	if err != nil {
+		return nil, err
+	}
+
+	// output_format is checked by callers; an unknown value keeps the
+	// returned type
+	format, _ := FormatMimeType(proj.OutputFormat)
+	ext := extension(format)
+	if ext == "" {
+		ext = ".png"
+	}
 
 	for _, theme := range proj.Themes {
 		for _, style := range styles {
 			for v := 1; v <= variants; v++ {
-				prompt := buildPrompt(proj, theme, style, stylePresets)
-				filename := buildFilename(theme, style, v)
+				prompt, err := templates.render(promptData(proj, theme, style, v, presets))
+				if err != nil {
+					return nil, err
+				}
+				filename := buildFilename(theme, style, v, ext)
 
 				prompts = append(prompts, PromptSpec{
 					Theme:    theme,
@@ -46,50 +90,63 @@ func GeneratePrompts(proj *config.Project, styles []string, variants int) []Prom
 					Variant:  v,
 					Prompt:   prompt,
 					Filename: filename,
+					Format:   format,
+					Options:  generationOptions(proj, presets[style], v),
 				})
 			}
 		}
 	}
 
-	return prompts
+	return prompts, nil
 }
 
-func buildPrompt(proj *config.Project, theme, style string, presets map[string]config.StylePreset) string {
-	var parts []string
-
-	// Base: logo description
-	parts = append(parts, fmt.Sprintf("A professional logo icon for '%s'", proj.Project))
-
-	if proj.Tagline != "" {
-		parts = append(parts, fmt.Sprintf("a %s tool", strings.ToLower(proj.Tagline)))
+// generationOptions maps project config onto request options for one
+// variant. A fixed seed is offset by the variant number so variants differ
+// but each stays reproducible. The style's negative keywords are added to
+// the negative prompt.
+func generationOptions(proj *config.Project, preset config.StylePreset, variant int) api.GenerationOptions {
+	g := proj.Generation
+	opts := api.GenerationOptions{
+		AspectRatio:        proj.AspectRatio,
+		ImageSize:          g.ImageSize,
+		Temperature:        g.Temperature,
+		CandidateCount:     g.CandidateCount,
+		ResponseModalities: g.ResponseModalities,
+		NegativePrompt:     g.NegativePrompt,
+		PersonGeneration:   g.PersonGeneration,
+		SafetyFilterLevel:  g.SafetyFilterLevel,
+		AddWatermark:       g.AddWatermark,
+		EnhancePrompt:      g.EnhancePrompt,
+		Language:           g.Language,
+		OutputMimeType:     g.OutputMimeType,
+		CompressionQuality: g.CompressionQuality,
+		IncludeRaiReason:   g.IncludeRaiReason,
 	}
-
-	// Theme
-	parts = append(parts, fmt.Sprintf("with a %s theme", theme))
-
-	// Style keywords
-	if preset, ok := presets[style]; ok {
-		parts = append(parts, strings.Join(preset.Keywords, ", "))
-	} else {
-		parts = append(parts, style+" style")
+	if len(preset.NegativeKeywords) > 0 {
+		negative := strings.Join(preset.NegativeKeywords, ", ")
+		if opts.NegativePrompt != "" {
+			negative = opts.NegativePrompt + ", " + negative
+		}
+		opts.NegativePrompt = negative
 	}
-
-	// Standard quality additions
-	parts = append(parts, "high quality", "suitable for app icon", "centered composition", "white or transparent background")
-
-	// Custom base prompt override
-	if proj.BasePrompt != "" {
-		return proj.BasePrompt + ". " + strings.Join(parts[2:], ", ")
+	if g.Seed != nil {
+		seed := *g.Seed + int32(variant-1)
+		opts.Seed = &seed
 	}
-
-	return strings.Join(parts, ", ")
+	for _, s := range g.SafetySettings {
+		opts.SafetySettings = append(opts.SafetySettings, api.SafetySetting{
+			Category:  s.Category,
+			Threshold: s.Threshold,
+		})
+	}
+	return opts
 }
 
-func buildFilename(theme, style string, variant int) string {
+func buildFilename(theme, style string, variant int, ext string) string {
 	// Sanitize names for filesystem
 	theme = sanitizeName(theme)
 	style = sanitizeName(style)
-	return fmt.Sprintf("%s-%s-%d.png", theme, style, variant)
+	return fmt.Sprintf("%s-%s-%d%s", theme, style, variant, ext)
 }
 
 func sanitizeName(s string) string {
@@ -99,59 +156,318 @@ func sanitizeName(s string) string {
 	return s
 }
 
-// GenerateImages calls the API for each prompt and saves results
-func GenerateImages(client *api.GeminiClient, prompts []PromptSpec, outDir string, verbose bool) ([]GenerationResult, error) {
-	var results []GenerationResult
-	ctx := context.Background()
+// Options controls how GenerateImages runs
+type Options struct {
+	Parallel int  // maximum concurrent generations (minimum 1)
+	Verbose  bool // print per-image progress
+	Resume   bool // skip prompts whose image and metadata already exist
 
+	// ChangedOnly skips prompts whose recorded hash still matches, so only
+	// new or stale images are generated
+	ChangedOnly bool
+
+	// Budget, if set, is charged Price for every request; prompts that
+	// would exceed it are not generated
+	Budget *budget.Budget
+	Price  float64
+}
+
+// GenerateImages calls the backend for each prompt and saves results.
+// Variants of the same prompt are requested together when the backend can
+// return several images per call (see planJobs). Requests are made by a
+// pool of opts.Parallel workers; results are returned in the same order as
+// prompts. An auth or quota failure stops further requests, since every
+// remaining prompt would fail the same way.
+//
+// If ctx is cancelled, in-flight requests are aborted, prompts not yet
+// started are marked cancelled and ctx.Err() is returned alongside the
+// complete results slice so callers can still record the run.
+func GenerateImages(ctx context.Context, backend api.Backend, prompts []PromptSpec, outDir string, opts Options) ([]GenerationResult, error) {
+	results := make([]GenerationResult, len(prompts))
+	removePartials(outDir)
+
+	// Skip up-to-date outputs before planning requests
+	var pending []int
 	for i, spec := range prompts {
-		if verbose {
-			fmt.Printf("[%d/%d] Generating %s...\n", i+1, len(prompts), spec.Filename)
+		if (opts.Resume && IsGenerated(outDir, spec)) || (opts.ChangedOnly && IsCurrent(outDir, spec, backend.Model())) {
+			// The saved image may not have the planned extension
+			outPath, _ := findImage(outDir, spec)
+			results[i] = GenerationResult{
+				Spec:     spec,
+				Success:  true,
+				Skipped:  true,
+				FilePath: outPath,
+			}
+			if opts.Verbose {
+				fmt.Printf("[%d/%d] Skipping %s (up to date)\n", i+1, len(prompts), spec.Filename)
+			}
+			continue
 		}
+		pending = append(pending, i)
+	}
 
-		result := GenerationResult{Spec: spec}
-		outPath := filepath.Join(outDir, spec.Filename)
+	jobs := planJobs(prompts, pending, backend.Capabilities())
 
-		// Generate image
-		imageData, err := client.GenerateImage(ctx, spec.Prompt)
-		if err != nil {
-			result.Error = err.Error()
-			result.Success = false
-			results = append(results, result)
+	var (
+		mu    sync.Mutex
+		fatal error
+	)
 
-			if verbose {
-				fmt.Printf("  Error: %v\n", err)
+	pool.Run(len(jobs), opts.Parallel, func(j int) {
+		job := jobs[j]
+
+		if ctx.Err() != nil {
+			for _, i := range job {
+				results[i] = GenerationResult{Spec: prompts[i], Error: "cancelled"}
 			}
-			continue
+			return
 		}
 
-		// Save image
-		if err := os.WriteFile(outPath, imageData, 0644); err != nil {
-			result.Error = fmt.Sprintf("save failed: %v", err)
-			result.Success = false
-			results = append(results, result)
-			continue
+		mu.Lock()
+		stop := fatal
+		mu.Unlock()
+		if stop != nil {
+			for _, i := range job {
+				results[i] = GenerationResult{Spec: prompts[i], Error: fmt.Sprintf("skipped: %v", stop)}
+			}
+			return
+		}
+
+		// Reserve budget per image; shrink the request to what fits. Other
+		// workers may release budget between reservations, so any variant
+		// of the job can be the one that does not fit.
+		var kept []int
+		for _, i := range job {
+			if err := opts.Budget.Reserve(opts.Price); err != nil {
+				results[i] = GenerationResult{Spec: prompts[i], Error: fmt.Sprintf("skipped: %v", err)}
+				continue
+			}
+			kept = append(kept, i)
+		}
+		job = kept
+		if len(job) == 0 {
+			return
+		}
+
+		specs := make([]PromptSpec, len(job))
+		for k, i := range job {
+			specs[k] = prompts[i]
+			if opts.Verbose {
+				fmt.Printf("[%d/%d] Generating %s...\n", i+1, len(prompts), prompts[i].Filename)
+			}
+		}
+
+		start := time.Now()
+		callCtx := api.WithLabels(ctx, "theme", specs[0].Theme, "style", specs[0].Style)
+		jobResults, err := generateJob(callCtx, backend, specs, outDir)
+		elapsed := time.Since(start)
+
+		for k, i := range job {
+			results[i] = jobResults[k]
+			// Each image carries its share of the request time
+			results[i].Duration = elapsed / time.Duration(len(job))
+			// Only images the API never returned go unbilled; ones that
+			// failed validation, transcoding or saving were still paid for
+			if !results[i].Success && (err != nil || results[i].Kind == kindMissing) {
+				opts.Budget.Release(opts.Price)
+			}
+			if opts.Verbose && ctx.Err() == nil {
+				if results[i].Success {
+					fmt.Printf("  Saved: %s\n", results[i].FilePath)
+				} else {
+					fmt.Printf("  Error: %s: %s\n", prompts[i].Filename, results[i].Error)
+				}
+			}
+		}
+
+		if kind := api.KindOf(err); kind == api.KindAuth || kind == api.KindQuota {
+			mu.Lock()
+			if fatal == nil {
+				fatal = err
+			}
+			mu.Unlock()
+		}
+	})
+
+	return results, ctx.Err()
+}
+
+// planJobs groups pending prompt indices into backend requests. Adjacent
+// variants of the same theme, style and prompt share a request, up to the
+// project's candidate_count or the backend's default, capped by what the
+// backend can return per call.
+func planJobs(prompts []PromptSpec, pending []int, caps api.Capabilities) [][]int {
+	var jobs [][]int
+	for _, i := range pending {
+		spec := prompts[i]
+
+		perCall := spec.Options.CandidateCount
+		if perCall < 1 {
+			perCall = caps.DefaultImagesPerCall
+		}
+		if perCall > caps.MaxImagesPerCall {
+			perCall = caps.MaxImagesPerCall
 		}
 
-		// Save metadata
-		metaPath := outPath[:len(outPath)-4] + ".json"
-		meta := map[string]interface{}{
-			"prompt":  spec.Prompt,
-			"theme":   spec.Theme,
-			"style":   spec.Style,
-			"variant": spec.Variant,
+		if n := len(jobs); n > 0 && perCall > 1 {
+			last := jobs[n-1]
+			first := prompts[last[0]]
+			if len(last) < perCall && first.Theme == spec.Theme && first.Style == spec.Style && first.Prompt == spec.Prompt {
+				jobs[n-1] = append(last, i)
+				continue
+			}
 		}
-		metaData, _ := json.MarshalIndent(meta, "", "  ")
-		os.WriteFile(metaPath, metaData, 0644)
+		jobs = append(jobs, []int{i})
+	}
+	return jobs
+}
+
+// generateJob makes one request for all specs (variants of one prompt)
+// and saves each returned image against its spec. The returned error is
+// the backend failure, if any, so callers can react to its classification.
+func generateJob(ctx context.Context, backend api.Backend, specs []PromptSpec, outDir string) ([]GenerationResult, error) {
+	results := make([]GenerationResult, len(specs))
+	for k, spec := range specs {
+		results[k] = GenerationResult{Spec: spec}
+	}
 
-		result.Success = true
-		result.FilePath = outPath
-		results = append(results, result)
+	// The first variant's options apply to the whole request
+	opts := specs[0].Options
+	if opts.CandidateCount != 0 || len(specs) > 1 {
+		opts.CandidateCount = len(specs)
+	}
 
-		if verbose {
-			fmt.Printf("  Saved: %s\n", outPath)
+	// Generate images
+	images, err := backend.Generate(ctx, specs[0].Prompt, opts)
+	if err != nil {
+		msg, kind := "cancelled", ""
+		if ctx.Err() == nil {
+			msg = api.Redact(err.Error())
+			if k := api.KindOf(err); k != api.KindUnknown {
+				kind = k.String()
+			}
 		}
+		for k := range results {
+			results[k].Error = msg
+			results[k].Kind = kind
+		}
+		return results, err
 	}
 
+	for k, spec := range specs {
+		if k >= len(images) {
+			// Backends drop filtered samples, and not every model honours
+			// the requested count, so the cause is unknown
+			results[k].Error = fmt.Sprintf("not returned by API (%d of %d images returned)", len(images), len(specs))
+			results[k].Kind = kindMissing
+			continue
+		}
+		results[k] = saveImage(spec, images[k], backend.Model(), outDir)
+	}
 	return results, nil
 }
+
+// Result kinds for failures that are not backend errors
+const (
+	kindInvalidImage = "invalid image"         // payload failed validation
+	kindMissing      = "missing from response" // fewer images returned than requested
+)
+
+// saveImage validates an image, transcodes it to the spec's format if one
+// is set, and writes it with its metadata sidecar. The file extension
+// follows the saved type, whatever the API claimed it to be.
+func saveImage(spec PromptSpec, img api.Image, model, outDir string) GenerationResult {
+	result := GenerationResult{Spec: spec}
+
+	decoded, err := decodeImage(img.Data)
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid image: %v", err)
+		result.Kind = kindInvalidImage
+		return result
+	}
+
+	data, mimeType := img.Data, decoded.mimeType
+	if spec.Format != "" && spec.Format != mimeType {
+		data, err = encodeImage(decoded, spec.Format, spec.Options.CompressionQuality)
+		if err != nil {
+			result.Error = fmt.Sprintf("transcode failed: %v", err)
+			return result
+		}
+		mimeType = spec.Format
+	}
+
+	planned := filepath.Join(outDir, spec.Filename)
+	outPath := strings.TrimSuffix(planned, filepath.Ext(planned)) + extension(mimeType)
+
+	// Save image. Writes go through a temp file so an interrupted run never
+	// leaves a truncated image behind.
+	if err := writeFileAtomic(outPath, data); err != nil {
+		result.Error = fmt.Sprintf("save failed: %v", err)
+		return result
+	}
+	removeStale(outPath)
+
+	// Save metadata
+	meta := Metadata{
+		Prompt:   spec.Prompt,
+		Theme:    spec.Theme,
+		Style:    spec.Style,
+		Variant:  spec.Variant,
+		Model:    model,
+		MimeType: mimeType,
+		Text:     img.Text,
+		Hash:     spec.Hash(model),
+	}
+	metaData, _ := json.MarshalIndent(meta, "", "  ")
+	// Prompts and model text are user and model supplied; never let them
+	// carry an API key to disk
+	writeFileAtomic(metadataPath(outPath), []byte(api.Redact(string(metaData))))
+
+	result.Success = true
+	result.FilePath = outPath
+	return result
+}
+
+// removeStale deletes earlier outputs for the same variant saved under a
+// different extension, so each variant has exactly one image
+func removeStale(outPath string) {
+	stem := strings.TrimSuffix(outPath, filepath.Ext(outPath))
+	for _, f := range formats {
+		if stem+f.ext != outPath {
+			os.Remove(stem + f.ext)
+		}
+	}
+}
+
+// partialPattern matches temp files left by an interrupted write
+const partialPattern = ".beautifi-*.tmp"
+
+// writeFileAtomic writes data to path via a temp file and rename
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), partialPattern)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// removePartials deletes temp files left behind by a force-quit run
+func removePartials(outDir string) {
+	matches, _ := filepath.Glob(filepath.Join(outDir, partialPattern))
+	for _, m := range matches {
+		os.Remove(m)
+	}
+}
@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/rickhallett/beautifi/internal/config"
+)
+
+// DefaultPromptTemplate renders the built-in logo prompt. base_prompt, when
+// set, replaces the opening description.
+const DefaultPromptTemplate = `{{if .BasePrompt}}{{.BasePrompt}}. {{else}}A professional logo icon for '{{.Project}}'{{with .Tagline}}, a {{lower .}} tool{{end}}, {{end}}` +
+	`with a {{.Theme}} theme, {{.Keywords}}, high quality, suitable for app icon, centered composition, white or transparent background` +
+	`{{with .PromptSuffix}}, {{.}}{{end}}`
+
+// PromptData is the data available to prompt templates
+type PromptData struct {
+	Project    string
+	Tagline    string
+	Theme      string
+	Style      string
+	Keywords   string // the style preset's keywords, comma separated
+	Variant    int
+	BasePrompt string
+	Extras     map[string]string
+
+	// From the style preset
+	Description      string
+	NegativeKeywords string // comma separated
+	PromptSuffix     string
+}
+
+// promptFuncs are the functions available to prompt templates
+var promptFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"join":  strings.Join,
+}
+
+// promptTemplates holds the parsed templates for one project
+type promptTemplates struct {
+	project *template.Template
+	styles  map[string]*template.Template
+}
+
+// parsePromptTemplates parses the project's prompt_template (or the default)
+// and any style_templates overrides
+func parsePromptTemplates(proj *config.Project) (*promptTemplates, error) {
+	text := proj.PromptTemplate
+	if text == "" {
+		text = DefaultPromptTemplate
+	}
+	project, err := parsePromptTemplate("prompt_template", text)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &promptTemplates{project: project, styles: map[string]*template.Template{}}
+	for style, text := range proj.StyleTemplates {
+		tmpl, err := parsePromptTemplate("style_templates."+style, text)
+		if err != nil {
+			return nil, err
+		}
+		t.styles[style] = tmpl
+	}
+	return t, nil
+}
+
+func parsePromptTemplate(name, text string) (*template.Template, error) {
+	// missingkey=error turns {{.Extras.typo}} into an error instead of "<no value>"
+	tmpl, err := template.New(name).Funcs(promptFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// render executes the template for data's style. Whitespace is collapsed
+// so templates can be written as multi-line YAML blocks.
+func (t *promptTemplates) render(data PromptData) (string, error) {
+	tmpl, ok := t.styles[data.Style]
+	if !ok {
+		tmpl = t.project
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering prompt for %s/%s: %w", data.Theme, data.Style, err)
+	}
+	return strings.Join(strings.Fields(b.String()), " "), nil
+}
+
+// promptData builds the template data for one prompt
+func promptData(proj *config.Project, theme, style string, variant int, presets map[string]config.StylePreset) PromptData {
+	preset, ok := presets[style]
+	keywords := style + " style"
+	if ok && len(preset.Keywords) > 0 {
+		keywords = strings.Join(preset.Keywords, ", ")
+	}
+
+	extras := proj.Extras
+	if extras == nil {
+		extras = map[string]string{}
+	}
+
+	return PromptData{
+		Project:    proj.Project,
+		Tagline:    proj.Tagline,
+		Theme:      theme,
+		Style:      style,
+		Keywords:   keywords,
+		Variant:    variant,
+		BasePrompt: proj.BasePrompt,
+		Extras:     extras,
+
+		Description:      preset.Description,
+		NegativeKeywords: strings.Join(preset.NegativeKeywords, ", "),
+		PromptSuffix:     preset.PromptSuffix,
+	}
+}
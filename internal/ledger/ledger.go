@@ -0,0 +1,178 @@
+package ledger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileName is the ledger file inside the config directory
+const FileName = "usage.jsonl"
+
+// Entry records one API call
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Project   string    `json:"project"`
+	Backend   string    `json:"backend"`
+	Model     string    `json:"model"`
+	Style     string    `json:"style,omitempty"`
+	Success   bool      `json:"success"`
+	Images    int       `json:"images"`
+	ErrorKind string    `json:"error_kind,omitempty"`
+	LatencyMS int64     `json:"latency_ms"`
+	CostUSD   float64   `json:"cost_usd"` // estimated from the pricing table
+}
+
+// Ledger is an append-only JSON Lines log of API calls
+type Ledger struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Open returns the ledger stored in cfgDir
+func Open(cfgDir string) *Ledger {
+	return &Ledger{path: filepath.Join(cfgDir, FileName)}
+}
+
+// Path returns the ledger file path
+func (l *Ledger) Path() string {
+	return l.path
+}
+
+// Append adds an entry to the ledger
+func (l *Ledger) Append(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("create ledger dir: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open ledger: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write ledger: %w", err)
+	}
+	return nil
+}
+
+// Read returns entries at or after since. A missing ledger is empty.
+// Malformed lines (e.g. a torn final write) are skipped.
+func (l *Ledger) Read(since time.Time) ([]Entry, error) {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open ledger: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			continue
+		}
+		if !e.Time.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read ledger: %w", err)
+	}
+	return entries, nil
+}
+
+// Group aggregates entries sharing a key
+type Group struct {
+	Key       string
+	Calls     int
+	Succeeded int
+	CostUSD   float64
+	Latency   time.Duration // total; see AvgLatency
+}
+
+// AvgLatency returns the mean call latency of the group
+func (g Group) AvgLatency() time.Duration {
+	if g.Calls == 0 {
+		return 0
+	}
+	return g.Latency / time.Duration(g.Calls)
+}
+
+// GroupKeys are the fields entries can be summarised by
+var GroupKeys = []string{"project", "backend", "model", "style", "day"}
+
+// Summarize groups entries by one of GroupKeys, sorted by cost descending
+func Summarize(entries []Entry, by string) ([]Group, error) {
+	key, err := keyFunc(by)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string]*Group{}
+	for _, e := range entries {
+		k := key(e)
+		if k == "" {
+			k = "(none)"
+		}
+		g, ok := groups[k]
+		if !ok {
+			g = &Group{Key: k}
+			groups[k] = g
+		}
+		g.Calls++
+		if e.Success {
+			g.Succeeded++
+		}
+		g.CostUSD += e.CostUSD
+		g.Latency += time.Duration(e.LatencyMS) * time.Millisecond
+	}
+
+	result := make([]Group, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if by == "day" {
+			return result[i].Key < result[j].Key
+		}
+		if result[i].CostUSD != result[j].CostUSD {
+			return result[i].CostUSD > result[j].CostUSD
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+func keyFunc(by string) (func(Entry) string, error) {
+	switch by {
+	case "project":
+		return func(e Entry) string { return e.Project }, nil
+	case "backend":
+		return func(e Entry) string { return e.Backend }, nil
+	case "model":
+		return func(e Entry) string { return e.Model }, nil
+	case "style":
+		return func(e Entry) string { return e.Style }, nil
+	case "day":
+		return func(e Entry) string { return e.Time.Local().Format("2006-01-02") }, nil
+	default:
+		return nil, fmt.Errorf("unknown grouping %q (use one of %v)", by, GroupKeys)
+	}
+}
@@ -0,0 +1,34 @@
+package pool
+
+import "sync"
+
+// Run calls fn once for every index in [0, n), using at most workers
+// goroutines. It returns when all calls have finished. Callers collect
+// results by index, so output order matches input order regardless of
+// completion order.
+func Run(n, workers int, fn func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
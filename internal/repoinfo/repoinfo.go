@@ -0,0 +1,333 @@
+// Package repoinfo reads what a source repository says about itself and
+// proposes a tagline and themes for its project config.
+package repoinfo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rickhallett/beautifi/internal/api"
+)
+
+// Info is what was found in a repository
+type Info struct {
+	Name        string   // package or module name, else the directory name
+	Description string   // manifest description, else the README's first paragraph
+	Keywords    []string // manifest keywords/topics
+	Readme      string   // start of the README, for model suggestions
+	Sources     []string // files the information came from
+}
+
+// Suggestion is a proposed tagline and theme list
+type Suggestion struct {
+	Tagline string   `json:"tagline"`
+	Themes  []string `json:"themes"`
+}
+
+// maxThemes caps suggested themes
+const maxThemes = 5
+
+// readmeExcerpt is how much of the README is kept for model suggestions
+const readmeExcerpt = 4000
+
+// Read collects information from the repository at dir: go.mod,
+// package.json, Cargo.toml and the README. Missing files are skipped.
+func Read(dir string) (*Info, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	abs, _ := filepath.Abs(dir)
+	info := &Info{Name: filepath.Base(abs)}
+
+	for _, read := range []func(string, *Info) error{readGoMod, readPackageJSON, readCargoToml, readReadme} {
+		if err := read(dir, info); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+func readGoMod(dir string, info *Info) error {
+	p := filepath.Join(dir, "go.mod")
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if mod, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			info.Name = moduleName(strings.Trim(strings.TrimSpace(mod), `"`))
+			info.Sources = append(info.Sources, p)
+			break
+		}
+	}
+	return scanner.Err()
+}
+
+// majorVersion matches the /vN suffix of a v2+ Go module path
+var majorVersion = regexp.MustCompile(`/v[0-9]+$`)
+
+// moduleName returns the last element of a module path, ignoring any
+// major version suffix: github.com/acme/bosun/v2 is bosun
+func moduleName(mod string) string {
+	return path.Base(majorVersion.ReplaceAllString(mod, ""))
+}
+
+func readPackageJSON(dir string, info *Info) error {
+	p := filepath.Join(dir, "package.json")
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var pkg struct {
+		Name        string   `json:"name"`
+		Description string   `json:"description"`
+		Keywords    []string `json:"keywords"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return fmt.Errorf("parse %s: %w", p, err)
+	}
+	if pkg.Name != "" {
+		// Drop npm scopes: @acme/widget -> widget
+		info.Name = path.Base(pkg.Name)
+	}
+	mergeInfo(info, pkg.Description, pkg.Keywords)
+	info.Sources = append(info.Sources, p)
+	return nil
+}
+
+// cargoField matches `key = "value"` and `key = ["a", "b"]` lines
+var cargoField = regexp.MustCompile(`^(\w+)\s*=\s*(.+)$`)
+
+// readCargoToml reads name, description and keywords from [package]. Only
+// single-line values are understood, which covers typical manifests.
+func readCargoToml(dir string, info *Info) error {
+	p := filepath.Join(dir, "Cargo.toml")
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		inPackage   bool
+		description string
+		keywords    []string
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inPackage = line == "[package]"
+			continue
+		}
+		m := cargoField.FindStringSubmatch(line)
+		if !inPackage || m == nil {
+			continue
+		}
+		switch m[1] {
+		case "name":
+			info.Name = strings.Trim(m[2], `"`)
+		case "description":
+			description = strings.Trim(m[2], `"`)
+		case "keywords", "categories":
+			var list []string
+			if json.Unmarshal([]byte(m[2]), &list) == nil {
+				keywords = append(keywords, list...)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	mergeInfo(info, description, keywords)
+	info.Sources = append(info.Sources, p)
+	return nil
+}
+
+func readReadme(dir string, info *Info) error {
+	for _, name := range []string{"README.md", "README", "README.txt", "readme.md"} {
+		p := filepath.Join(dir, name)
+		data, err := os.ReadFile(p)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		text := string(data)
+		if len(text) > readmeExcerpt {
+			text = text[:readmeExcerpt]
+		}
+		info.Readme = text
+		mergeInfo(info, firstParagraph(text), nil)
+		info.Sources = append(info.Sources, p)
+		return nil
+	}
+	return nil
+}
+
+// mergeInfo keeps the first description found and adds new keywords
+func mergeInfo(info *Info, description string, keywords []string) {
+	if info.Description == "" {
+		info.Description = strings.TrimSpace(description)
+	}
+	for _, k := range keywords {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k != "" && !slices.Contains(info.Keywords, k) {
+			info.Keywords = append(info.Keywords, k)
+		}
+	}
+}
+
+// firstParagraph returns the first prose paragraph of a markdown document,
+// skipping headings, badges, HTML and code blocks
+func firstParagraph(text string) string {
+	var para []string
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		skip := inCode || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[!") ||
+			strings.HasPrefix(line, "![") || strings.HasPrefix(line, "<") || strings.HasPrefix(line, "=")
+		if line == "" || skip {
+			if len(para) > 0 {
+				break
+			}
+			continue
+		}
+		para = append(para, line)
+	}
+	return strings.Join(para, " ")
+}
+
+// Suggest proposes a tagline and themes from the repository's own text
+func Suggest(info *Info) Suggestion {
+	s := Suggestion{Tagline: tagline(info.Description)}
+
+	for _, k := range info.Keywords {
+		if len(s.Themes) == maxThemes {
+			break
+		}
+		s.Themes = append(s.Themes, k)
+	}
+	if len(s.Themes) == 0 {
+		s.Themes = frequentWords(info.Description+" "+firstParagraph(info.Readme), info.Name, 3)
+	}
+	return s
+}
+
+// SuggestWithModel asks a text model for a tagline and themes
+func SuggestWithModel(ctx context.Context, gen api.TextGenerator, info *Info) (Suggestion, error) {
+	prompt := fmt.Sprintf(`You are naming and branding a software project. Based on the information below, propose:
+- "tagline": a 2-5 word description of what the tool is (e.g. "Fleet manager"), no trailing punctuation
+- "themes": %d short visual themes for its logo (single words or short phrases, e.g. "ocean", "navigation")
+
+Reply with a JSON object with exactly those two keys.
+
+Project name: %s
+Description: %s
+Keywords: %s
+
+README excerpt:
+%s`, maxThemes-2, info.Name, info.Description, strings.Join(info.Keywords, ", "), info.Readme)
+
+	reply, err := gen.GenerateText(ctx, prompt, true)
+	if err != nil {
+		return Suggestion{}, err
+	}
+
+	var s Suggestion
+	if err := json.Unmarshal([]byte(strings.TrimSpace(reply)), &s); err != nil {
+		return Suggestion{}, fmt.Errorf("unexpected model reply: %w", err)
+	}
+	if len(s.Themes) > maxThemes {
+		s.Themes = s.Themes[:maxThemes]
+	}
+	return s, nil
+}
+
+// tagline shortens a description to its first clause
+func tagline(description string) string {
+	t := description
+	if i := strings.IndexAny(t, ".!?\n"); i >= 0 {
+		t = t[:i]
+	}
+	for _, sep := range []string{" - ", " – ", " — ", ": ", ", ", " that ", " which ", " using ", " with ", " built "} {
+		if i := strings.Index(t, sep); i > 0 {
+			t = t[:i]
+		}
+	}
+	t = strings.TrimSpace(t)
+	for _, article := range []string{"A ", "An ", "The "} {
+		t = strings.TrimPrefix(t, article)
+	}
+	if words := strings.Fields(t); len(words) > 6 {
+		t = strings.Join(words[:6], " ")
+	}
+	if r, size := utf8.DecodeRuneInString(t); size > 0 {
+		t = string(unicode.ToUpper(r)) + t[size:]
+	}
+	return t
+}
+
+// stopWords are ignored when picking themes from prose
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"can": true, "for": true, "from": true, "in": true, "into": true, "is": true, "it": true,
+	"its": true, "of": true, "on": true, "or": true, "that": true, "the": true, "this": true,
+	"to": true, "with": true, "you": true, "your": true, "use": true, "using": true, "tool": true,
+	"cli": true, "simple": true, "small": true, "fast": true, "lets": true, "makes": true,
+}
+
+var wordPattern = regexp.MustCompile(`[A-Za-z][A-Za-z-]{2,}`)
+
+// frequentWords returns the n most frequent meaningful words in text,
+// excluding the project's own name
+func frequentWords(text, name string, n int) []string {
+	counts := map[string]int{}
+	var order []string
+	for _, w := range wordPattern.FindAllString(strings.ToLower(text), -1) {
+		if stopWords[w] || w == strings.ToLower(name) {
+			continue
+		}
+		if counts[w] == 0 {
+			order = append(order, w)
+		}
+		counts[w]++
+	}
+	// Stable sort keeps first-mentioned words ahead on ties
+	sort.SliceStable(order, func(i, j int) bool { return counts[order[i]] > counts[order[j]] })
+	if len(order) > n {
+		order = order[:n]
+	}
+	return order
+}
@@ -0,0 +1,224 @@
+package runs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rickhallett/beautifi/internal/api"
+	"github.com/rickhallett/beautifi/internal/config"
+	"github.com/rickhallett/beautifi/internal/generator"
+)
+
+// dirName is the directory inside a project's output dir holding manifests
+const dirName = ".runs"
+
+// Manifest records a single generate invocation
+type Manifest struct {
+	ID        string    `json:"id"`
+	Project   string    `json:"project"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	Args      []string  `json:"args"`
+	Backend   string    `json:"backend"`
+	Model     string    `json:"model"`
+	RetryOf   string    `json:"retry_of,omitempty"`  // run whose failures this run retried
+	Cancelled bool      `json:"cancelled,omitempty"` // interrupted before all prompts ran
+
+	Config  *config.Project              `json:"config"`
+	Results []generator.GenerationResult `json:"results"`
+}
+
+// NewID returns a sortable, unique run ID for a run started at t
+func NewID(t time.Time) string {
+	suffix := make([]byte, 2)
+	rand.Read(suffix)
+	return t.UTC().Format("20060102-150405.000") + "-" + hex.EncodeToString(suffix)
+}
+
+// Succeeded returns the number of successful results, including skipped ones
+func (m *Manifest) Succeeded() int {
+	n := 0
+	for _, r := range m.Results {
+		if r.Success {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed returns the results that did not succeed
+func (m *Manifest) Failed() []generator.GenerationResult {
+	var failed []generator.GenerationResult
+	for _, r := range m.Results {
+		if !r.Success {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// FailedSpecs returns the prompt specs of failed results, for resubmission
+func (m *Manifest) FailedSpecs() []generator.PromptSpec {
+	var specs []generator.PromptSpec
+	for _, r := range m.Failed() {
+		specs = append(specs, r.Spec)
+	}
+	return specs
+}
+
+// Duration returns the wall time of the run
+func (m *Manifest) Duration() time.Duration {
+	return m.EndedAt.Sub(m.StartedAt)
+}
+
+// Dir returns the manifest directory for a project output dir
+func Dir(projectOutDir string) string {
+	return filepath.Join(projectOutDir, dirName)
+}
+
+// Save writes the manifest into projectOutDir
+func Save(projectOutDir string, m *Manifest) error {
+	dir := Dir(projectOutDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create runs dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	// Manifests hold error messages and are shared in CI; strip any key
+	data = []byte(api.Redact(string(data)))
+
+	if err := os.WriteFile(filepath.Join(dir, m.ID+".json"), data, 0644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+// Load reads a manifest by run ID. A unique prefix of the ID is accepted.
+func Load(projectOutDir, id string) (*Manifest, error) {
+	ids, err := listIDs(projectOutDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var match string
+	for _, candidate := range ids {
+		if candidate == id {
+			match = candidate
+			break
+		}
+		if strings.HasPrefix(candidate, id) {
+			if match != "" {
+				return nil, fmt.Errorf("run ID %q is ambiguous", id)
+			}
+			match = candidate
+		}
+	}
+	if match == "" {
+		return nil, fmt.Errorf("no run %q in %s", id, Dir(projectOutDir))
+	}
+
+	return readManifest(filepath.Join(Dir(projectOutDir), match+".json"))
+}
+
+// List returns all manifests for a project, oldest first
+func List(projectOutDir string) ([]*Manifest, error) {
+	ids, err := listIDs(projectOutDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []*Manifest
+	for _, id := range ids {
+		m, err := readManifest(filepath.Join(Dir(projectOutDir), id+".json"))
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+	sort.SliceStable(manifests, func(i, j int) bool {
+		return manifests[i].StartedAt.Before(manifests[j].StartedAt)
+	})
+	return manifests, nil
+}
+
+// Latest returns the most recent manifest for a project
+func Latest(projectOutDir string) (*Manifest, error) {
+	ids, err := listIDs(projectOutDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no runs recorded in %s", Dir(projectOutDir))
+	}
+	return readManifest(filepath.Join(Dir(projectOutDir), ids[len(ids)-1]+".json"))
+}
+
+// AverageDuration returns the mean duration of images actually generated
+// by backend and model in past runs of a project, and how many images it
+// is based on. Skipped and failed results are ignored.
+func AverageDuration(projectOutDir, backend, model string) (time.Duration, int) {
+	manifests, err := List(projectOutDir)
+	if err != nil {
+		return 0, 0
+	}
+
+	var total time.Duration
+	n := 0
+	for _, m := range manifests {
+		if m.Backend != backend || m.Model != model {
+			continue
+		}
+		for _, r := range m.Results {
+			if r.Success && !r.Skipped && r.Duration > 0 {
+				total += r.Duration
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return total / time.Duration(n), n
+}
+
+// listIDs returns run IDs sorted oldest first; IDs sort chronologically
+func listIDs(projectOutDir string) ([]string, error) {
+	entries, err := os.ReadDir(Dir(projectOutDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read runs dir: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func readManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %w", filepath.Base(path), err)
+	}
+	return &m, nil
+}
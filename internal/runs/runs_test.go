@@ -0,0 +1,91 @@
+package runs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rickhallett/beautifi/internal/api"
+	"github.com/rickhallett/beautifi/internal/config"
+	"github.com/rickhallett/beautifi/internal/generator"
+)
+
+const testKey = "runs-test-secret-5f3a9c"
+
+// leakyBackend echoes the API key back: in the text of images it returns,
+// and in the error for prompts containing "fail"
+type leakyBackend struct {
+	*api.StubClient
+}
+
+func (b leakyBackend) Generate(ctx context.Context, prompt string, opts api.GenerationOptions) ([]api.Image, error) {
+	if strings.Contains(prompt, "fail") {
+		return nil, fmt.Errorf("POST https://example.com/v1/models/stub:predict?key=%s: invalid key %s", testKey, testKey)
+	}
+	images, err := b.StubClient.Generate(ctx, prompt, opts)
+	for i := range images {
+		images[i].Text = "generated with " + testKey
+	}
+	return images, err
+}
+
+func TestSaveRedactsKeys(t *testing.T) {
+	api.RegisterSecret(testKey)
+
+	projectOutDir := t.TempDir()
+	backend := leakyBackend{api.NewStubClient()}
+	prompts := []generator.PromptSpec{
+		{Theme: "ocean", Style: "flat-minimal", Variant: 1, Prompt: "an ocean logo", Filename: "ocean-flat-minimal-1.png"},
+		{Theme: "ocean", Style: "geometric", Variant: 1, Prompt: "a logo that will fail", Filename: "ocean-geometric-1.png"},
+	}
+
+	start := time.Now()
+	results, err := generator.GenerateImages(context.Background(), backend, prompts, projectOutDir, generator.Options{Parallel: 1})
+	if err != nil {
+		t.Fatalf("GenerateImages: %v", err)
+	}
+	if !results[0].Success || results[1].Success {
+		t.Fatalf("want first prompt to succeed and second to fail, got %+v", results)
+	}
+
+	m := &Manifest{
+		ID:        NewID(start),
+		Project:   "leaky",
+		StartedAt: start,
+		EndedAt:   time.Now(),
+		Backend:   backend.Name(),
+		Model:     backend.Model(),
+		Config:    &config.Project{Project: "leaky"},
+		Results:   results,
+	}
+	if err := Save(projectOutDir, m); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	var checked int
+	err = filepath.Walk(projectOutDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(string(data), testKey) {
+			t.Errorf("%s contains the API key:\n%s", path, data)
+		}
+		checked++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One image sidecar and one manifest
+	if checked != 2 {
+		t.Errorf("checked %d .json files, want 2", checked)
+	}
+}